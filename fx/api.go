@@ -0,0 +1,219 @@
+package fx
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// maxRequestBody bounds the JSON body decoded by Handle.
+const maxRequestBody = 1 << 20
+
+// Validator is implemented by request types that check themselves after decoding. Errors that are not a Problem answer 400
+// with their message as the detail.
+type Validator interface {
+	Validate() error
+}
+
+// Handle registers a typed JSON endpoint for method and path on the router.
+//
+// The JSON body is decoded into Req, then fields tagged `path:"name"` are set from route variables and fields tagged
+// `query:"name"` from the query string. Fields tagged `validate:"required"` must be non-zero, and Req's Validate method
+// runs when it has one. Decoding and validation failures answer 400, and the handler result is written with Respond.
+func Handle[Req, Resp any](f *Fx, method, path string, handler func(context.Context, Req) (Resp, error), options ...RespondOption) *mux.Route {
+	route := f.Zero.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		var req Req
+		if err := decodeRequest(r, &req); err != nil {
+			f.WriteProblem(w, r, err)
+			return
+		}
+		f.Respond(w, r, func() (any, error) {
+			return handler(r.Context(), req)
+		}, options...)
+	}).Methods(method)
+	f.operations[route] = &operation{request: reflect.TypeFor[Req](), response: reflect.TypeFor[Resp]()}
+	return route
+}
+
+// decodeRequest fills target from the request body, route variables and query string, then validates it.
+func decodeRequest(r *http.Request, target any) error {
+	if r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0 {
+		decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxRequestBody))
+		if err := decoder.Decode(target); err != nil && !errors.Is(err, io.EOF) {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return NewProblem(http.StatusRequestEntityTooLarge, fmt.Sprintf("body exceeds %d bytes", maxRequestBody))
+			}
+			return Problemf(http.StatusBadRequest, "invalid JSON body: %w", err)
+		}
+	}
+
+	v := reflect.ValueOf(target).Elem()
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Struct {
+		vars := mux.Vars(r)
+		query := r.URL.Query()
+		// Fields promoted from embedded structs are decoded too, matching the OpenAPI description.
+		for _, field := range reflect.VisibleFields(v.Type()) {
+			if !field.IsExported() || field.Anonymous {
+				continue
+			}
+			if name, ok := field.Tag.Lookup("path"); ok {
+				if value, exists := vars[name]; exists {
+					if err := setFieldAt(v, field.Index, []string{value}); err != nil {
+						return Problemf(http.StatusBadRequest, "invalid path parameter %s: %w", name, err)
+					}
+				}
+			}
+			if name, ok := field.Tag.Lookup("query"); ok {
+				if values, exists := query[name]; exists {
+					if err := setFieldAt(v, field.Index, values); err != nil {
+						return Problemf(http.StatusBadRequest, "invalid query parameter %s: %w", name, err)
+					}
+				}
+			}
+			if field.Tag.Get("validate") == "required" {
+				if value, err := v.FieldByIndexErr(field.Index); err != nil || value.IsZero() {
+					return Problemf(http.StatusBadRequest, "%s is required", fieldName(field))
+				}
+			}
+		}
+	}
+
+	if validator, ok := target.(Validator); ok {
+		return validationProblem(validator.Validate())
+	}
+	if validator, ok := reflect.ValueOf(target).Elem().Interface().(Validator); ok {
+		return validationProblem(validator.Validate())
+	}
+	return nil
+}
+
+// fieldByIndex returns the nested field of v at index, allocating the nil embedded struct pointers on the way.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return v, fmt.Errorf("cannot set embedded pointer to unexported struct %s", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+// setFieldAt parses values into the nested field of v at index, see setField.
+func setFieldAt(v reflect.Value, index []int, values []string) error {
+	field, err := fieldByIndex(v, index)
+	if err != nil {
+		return err
+	}
+	return setField(field, values)
+}
+
+func validationProblem(err error) error {
+	var problem *Problem
+	if err == nil || errors.As(err, &problem) {
+		return err
+	}
+	problem = NewProblem(http.StatusBadRequest, err.Error())
+	problem.Err = err
+	return problem
+}
+
+// fieldName returns the name a client uses for field: its path, query or json name.
+func fieldName(field reflect.StructField) string {
+	for _, key := range []string{"path", "query", "json"} {
+		if name, _, _ := strings.Cut(field.Tag.Get(key), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+var textUnmarshaler = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// setField parses values into a string, bool, number, TextUnmarshaler, pointer to one, or slice of them.
+func setField(field reflect.Value, values []string) error {
+	if reflect.PointerTo(field.Type()).Implements(textUnmarshaler) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(values[0]))
+	}
+
+	switch field.Kind() {
+	case reflect.Pointer:
+		value := reflect.New(field.Type().Elem())
+		if err := setField(value.Elem(), values); err != nil {
+			return err
+		}
+		field.Set(value)
+		return nil
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.Uint8 {
+			field.SetBytes([]byte(values[0]))
+			return nil
+		}
+		var parts []string
+		for _, value := range values {
+			parts = append(parts, strings.Split(value, ",")...)
+		}
+		slice := reflect.MakeSlice(field.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setField(slice.Index(i), []string{part}); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+
+	value := values[0]
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not an unsigned integer", value)
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		field.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
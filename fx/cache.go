@@ -0,0 +1,347 @@
+package fx
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// CacheStore persists cached responses for Fx requests.
+type CacheStore interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// UseCache caches GET responses in store, honoring Cache-Control, ETag and Last-Modified. Pass nil to disable.
+func (f *Fx) UseCache(store CacheStore) {
+	f.cache = store
+}
+
+// NewMemoryStore returns an in-process CacheStore holding up to budget bytes, evicting the least recently used.
+func NewMemoryStore(budget int64) CacheStore {
+	return &memoryStore{cache: newByteCache(budget)}
+}
+
+// RedisStore returns a CacheStore on the client from ConnectRedis, namespacing keys with prefix.
+func (f *Fx) RedisStore(prefix string) (CacheStore, error) {
+	if f.redis == nil {
+		return nil, errors.New("redis is not connected, call ConnectRedis first")
+	}
+	return &redisStore{client: f.redis, prefix: prefix}, nil
+}
+
+// Cache option builders
+type cacheSettings struct {
+	store      CacheStore
+	bypass     bool
+	ttl        time.Duration
+	revalidate bool
+}
+
+func cacheOf(req *http.Request) *cacheSettings {
+	settings := settingsOf(req)
+	if settings.cache == nil {
+		settings.cache = &cacheSettings{}
+	}
+	return settings.cache
+}
+
+// WithCache caches the request in store instead of the store set by UseCache.
+func WithCache(store CacheStore) GetOption {
+	return func(req *http.Request) {
+		cacheOf(req).store = store
+	}
+}
+
+// WithoutCache neither reads nor writes the cache for the request.
+func WithoutCache() GetOption {
+	return func(req *http.Request) {
+		cacheOf(req).bypass = true
+	}
+}
+
+// WithCacheTTL treats the response as fresh for ttl regardless of its caching headers.
+func WithCacheTTL(ttl time.Duration) GetOption {
+	return func(req *http.Request) {
+		cacheOf(req).ttl = ttl
+	}
+}
+
+// WithRevalidate revalidates a cached response with the upstream even when it is still fresh.
+func WithRevalidate() GetOption {
+	return func(req *http.Request) {
+		cacheOf(req).revalidate = true
+	}
+}
+
+// cacheFor returns the store caching req, or nil when the request is not cached.
+func (f *Fx) cacheFor(req *http.Request) CacheStore {
+	if req.Method != http.MethodGet {
+		return nil
+	}
+	settings := settingsOf(req).cache
+	if settings == nil {
+		return f.cache
+	}
+	if settings.bypass {
+		return nil
+	}
+	if settings.store != nil {
+		return settings.store
+	}
+	return f.cache
+}
+
+// cacheEntry is a stored response and the validators to revalidate it.
+type cacheEntry struct {
+	Body         []byte    `json:"body"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Expires      time.Time `json:"expires"`
+}
+
+// executeCached serves req from store while fresh, revalidates it when stale and stores cacheable responses.
+func (f *Fx) executeCached(req *http.Request, store CacheStore) ([]byte, error) {
+	settings := settingsOf(req).cache
+	if settings == nil {
+		settings = &cacheSettings{}
+	}
+	key := cacheKey(req)
+
+	var entry *cacheEntry
+	if data, ok, err := store.Get(req.Context(), key); err == nil && ok {
+		entry = &cacheEntry{}
+		if json.Unmarshal(data, entry) != nil {
+			entry = nil
+		}
+	}
+	if entry != nil && !settings.revalidate && time.Now().Before(entry.Expires) {
+		return entry.Body, nil
+	}
+	if entry != nil {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := f.do(req)
+	var httpErr *HTTPError
+	if err != nil && entry != nil && errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotModified {
+		f.storeEntry(req, store, key, entry, httpErr.Header, settings.ttl)
+		return entry.Body, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	header := resp.Header
+	body, err := readBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	entry = &cacheEntry{
+		Body:         body,
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+	}
+	f.storeEntry(req, store, key, entry, header, settings.ttl)
+	return body, nil
+}
+
+// storeEntry computes the entry's freshness from header and writes it, skipping responses that may not be stored.
+func (f *Fx) storeEntry(req *http.Request, store CacheStore, key string, entry *cacheEntry, header http.Header, ttl time.Duration) {
+	fresh, storable := freshness(header)
+	if ttl > 0 {
+		fresh, storable = ttl, true
+	}
+	validated := entry.ETag != "" || entry.LastModified != ""
+	if !storable || (fresh <= 0 && !validated) {
+		return
+	}
+
+	entry.Expires = time.Now().Add(fresh)
+	retain := fresh
+	if validated {
+		retain += 24 * time.Hour
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	_ = store.Set(req.Context(), key, data, retain)
+}
+
+// freshness returns how long a response stays fresh and whether it may be stored at all.
+func freshness(header http.Header) (time.Duration, bool) {
+	var maxAge time.Duration
+	hasMaxAge := false
+	for directive := range strings.SplitSeq(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store":
+			return 0, false
+		case "no-cache":
+			return 0, true
+		case "max-age":
+			if seconds, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil {
+				maxAge, hasMaxAge = time.Duration(seconds)*time.Second, true
+			}
+		}
+	}
+
+	if !hasMaxAge {
+		expires, err := http.ParseTime(header.Get("Expires"))
+		if err != nil {
+			return 0, true
+		}
+		date, err := http.ParseTime(header.Get("Date"))
+		if err != nil {
+			date = time.Now()
+		}
+		return expires.Sub(date), true
+	}
+	if age, err := strconv.Atoi(header.Get("Age")); err == nil {
+		maxAge -= time.Duration(age) * time.Second
+	}
+	return maxAge, true
+}
+
+// cacheKey identifies a response by URL and the request headers that change it, including every credential header,
+// so responses are never shared between credentials.
+func cacheKey(req *http.Request) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s", req.URL.String(), req.Header.Get("Accept"))
+	for _, name := range redacted {
+		fmt.Fprintf(hash, "\n%q", req.Header.Values(name))
+	}
+	return "fx:http:" + hex.EncodeToString(hash.Sum(nil))
+}
+
+// memoryStore is a CacheStore on a byteCache.
+type memoryStore struct {
+	cache *byteCache
+}
+
+func (m *memoryStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	data, ok := m.cache.get(key)
+	return data, ok, nil
+}
+
+func (m *memoryStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+	m.cache.put(key, value, expires)
+	return nil
+}
+
+// redisStore is a CacheStore on a Redis client.
+type redisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+func (r *redisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	data, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+func (r *redisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, r.prefix+key, value, ttl).Err()
+}
+
+// byteCache holds values in memory up to budget bytes, evicting the least recently used and expired ones.
+type byteCache struct {
+	sync.Mutex
+	budget int64
+	used   int64
+	lru    *list.List
+	items  map[string]*list.Element
+}
+
+type cachedBytes struct {
+	key     string
+	data    []byte
+	expires time.Time
+}
+
+func newByteCache(budget int64) *byteCache {
+	return &byteCache{
+		budget: budget,
+		lru:    list.New(),
+		items:  make(map[string]*list.Element),
+	}
+}
+
+func (c *byteCache) get(key string) ([]byte, bool) {
+	c.Lock()
+	defer c.Unlock()
+	el, exists := c.items[key]
+	if !exists {
+		return nil, false
+	}
+	entry := el.Value.(*cachedBytes)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return entry.data, true
+}
+
+// put stores data under key until expires, or until evicted when expires is zero.
+func (c *byteCache) put(key string, data []byte, expires time.Time) {
+	size := int64(len(data))
+	if size > c.budget {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if el, exists := c.items[key]; exists {
+		c.remove(el)
+	}
+	for c.used+size > c.budget {
+		c.remove(c.lru.Back())
+	}
+	c.items[key] = c.lru.PushFront(&cachedBytes{key: key, data: data, expires: expires})
+	c.used += size
+}
+
+// resize changes the budget, evicting the least recently used values that no longer fit.
+func (c *byteCache) resize(budget int64) {
+	c.Lock()
+	defer c.Unlock()
+	c.budget = budget
+	for c.used > c.budget {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *byteCache) remove(el *list.Element) {
+	entry := el.Value.(*cachedBytes)
+	c.lru.Remove(el)
+	delete(c.items, entry.key)
+	c.used -= int64(len(entry.data))
+}
@@ -0,0 +1,133 @@
+package fx
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// ClientOption configures the Http client built by InitWithClient and NewClient.
+type ClientOption func(*clientConfig)
+
+type clientConfig struct {
+	timeout        time.Duration
+	dialTimeout    time.Duration
+	headerTimeout  time.Duration
+	maxIdle        int
+	maxIdlePerHost int
+	idleTimeout    time.Duration
+	proxy          func(*http.Request) (*url.URL, error)
+	caBundles      []string
+	minTLS         uint16
+	wrappers       []func(http.RoundTripper) http.RoundTripper
+}
+
+// WithClientTimeout bounds every request made with the client, including reading the body. Defaults to 60s, 0 disables it for long streamed responses.
+func WithClientTimeout(timeout time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		c.timeout = timeout
+	}
+}
+
+// WithDialTimeouts bounds connecting and waiting for response headers. Defaults to 10s and 30s.
+func WithDialTimeouts(dial, responseHeader time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		c.dialTimeout = dial
+		c.headerTimeout = responseHeader
+	}
+}
+
+// WithConnectionPool sizes the pool of idle keep-alive connections. Defaults to 100, 10 per host and 90s.
+func WithConnectionPool(maxIdle, maxIdlePerHost int, idleTimeout time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		c.maxIdle = maxIdle
+		c.maxIdlePerHost = maxIdlePerHost
+		c.idleTimeout = idleTimeout
+	}
+}
+
+// WithProxy selects the proxy for each request. Defaults to HTTP_PROXY, HTTPS_PROXY and NO_PROXY from the environment.
+func WithProxy(proxy func(*http.Request) (*url.URL, error)) ClientOption {
+	return func(c *clientConfig) {
+		c.proxy = proxy
+	}
+}
+
+// WithCABundle trusts the PEM certificates in path in addition to the system roots.
+func WithCABundle(path string) ClientOption {
+	return func(c *clientConfig) {
+		c.caBundles = append(c.caBundles, path)
+	}
+}
+
+// WithMinTLS sets the minimum TLS version, such as tls.VersionTLS13. Defaults to TLS 1.2.
+func WithMinTLS(version uint16) ClientOption {
+	return func(c *clientConfig) {
+		c.minTLS = version
+	}
+}
+
+// WithTransportWrapper wraps the client transport for logging, metrics or auth. Wrappers apply in order, the last one outermost.
+func WithTransportWrapper(wrap func(http.RoundTripper) http.RoundTripper) ClientOption {
+	return func(c *clientConfig) {
+		c.wrappers = append(c.wrappers, wrap)
+	}
+}
+
+// NewClient builds an Http client with timeouts, connection pooling and the proxy from the environment.
+func NewClient(options ...ClientOption) (*http.Client, error) {
+	config := &clientConfig{
+		timeout:        60 * time.Second,
+		dialTimeout:    10 * time.Second,
+		headerTimeout:  30 * time.Second,
+		maxIdle:        100,
+		maxIdlePerHost: 10,
+		idleTimeout:    90 * time.Second,
+		proxy:          http.ProxyFromEnvironment,
+		minTLS:         tls.VersionTLS12,
+	}
+	for _, option := range options {
+		option(config)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: config.minTLS}
+	if len(config.caBundles) > 0 {
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		for _, path := range config.caBundles {
+			pem, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA bundle %s: %w", path, err)
+			}
+			if !roots.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in CA bundle %s", path)
+			}
+		}
+		tlsConfig.RootCAs = roots
+	}
+
+	dialer := &net.Dialer{Timeout: config.dialTimeout, KeepAlive: 30 * time.Second}
+	var transport http.RoundTripper = &http.Transport{
+		Proxy:                 config.proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: config.headerTimeout,
+		ExpectContinueTimeout: time.Second,
+		MaxIdleConns:          config.maxIdle,
+		MaxIdleConnsPerHost:   config.maxIdlePerHost,
+		IdleConnTimeout:       config.idleTimeout,
+		ForceAttemptHTTP2:     true,
+	}
+	for _, wrap := range config.wrappers {
+		transport = wrap(transport)
+	}
+	return &http.Client{Transport: transport, Timeout: config.timeout}, nil
+}
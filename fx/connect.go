@@ -3,67 +3,16 @@ package fx
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
-	"io"
-	"log"
 	"net/http"
 	"time"
 
-	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/ethereum/go-ethereum/rpc"
 	_ "github.com/lib/pq"
 	"github.com/redis/go-redis/v9"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
 )
 
-// Establish geth.ipc connection
-func (f *Fx) Node() error {
-	rpc, err := rpc.DialIPC(f.Context, "/.ethereum/geth.ipc") // Updated path
-	if err != nil {
-		log.Printf("Failed to connect to the Ethereum client: %v", err)
-		return nil
-	}
-	// log.Println("Successfully connected to the Ethereum client.")
-	eth := ethclient.NewClient(rpc)
-	f.Rpc = rpc
-	f.Eth = eth
-	return nil
-}
-
-// GethHandler handles JSON-RPC requests via IPC
-func (f *Fx) GethHandler(w http.ResponseWriter, r *http.Request) {
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
-		return
-	}
-
-	// Parse the JSON-RPC request
-	var req struct {
-		Method string `json:"method"`
-		Params []any  `json:"params"`
-	}
-	if err := json.Unmarshal(body, &req); err != nil {
-		http.Error(w, "Invalid JSON-RPC request", http.StatusBadRequest)
-		return
-	}
-
-	ctx, cancel := context.WithTimeout(f.Context, 30*time.Second)
-	defer cancel()
-
-	var result json.RawMessage
-	if err := f.Rpc.CallContext(ctx, &result, req.Method, req.Params...); err != nil {
-		http.Error(w, "RPC call failed", http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Write(result)
-}
-
 func (f *Fx) ConnectRedis(dbNumber int, password string) (*redis.Client, error) {
 	client := redis.NewClient(&redis.Options{
 		Addr:     "redis:6379",
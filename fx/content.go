@@ -1,46 +1,255 @@
 package fx
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
 	"mime"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
 )
 
-// Walk directory and load files into memory, determine Content-Type based on file extension. Register route/<prefix/<file without extension>.
-func (f *Fx) AddPath(dir string, prefix string) {
-	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() {
+// PathOption configures how AddPath maps files to routes.
+type PathOption func(*pathConfig)
+
+type pathConfig struct {
+	keepExt     bool
+	index       []string
+	streamAbove int64
+	budget      int64
+}
+
+// WithExtensions keeps file extensions in routes, so a.css and a.js register as /prefix/a.css and /prefix/a.js.
+func WithExtensions() PathOption {
+	return func(c *pathConfig) {
+		c.keepExt = true
+	}
+}
+
+// WithIndex sets the file names served at their directory URL. Defaults to index.html and index.htm.
+func WithIndex(names ...string) PathOption {
+	return func(c *pathConfig) {
+		c.index = names
+	}
+}
+
+// WithStreamThreshold serves files larger than size bytes from disk on every request instead of caching them. Defaults to 4 MiB.
+func WithStreamThreshold(size int64) PathOption {
+	return func(c *pathConfig) {
+		c.streamAbove = size
+	}
+}
+
+// WithMemoryBudget caps the bytes of file content held in memory, evicting the least recently served files first.
+// The budget is shared by the files of every AddPath and AddFS call, the last one setting it applies. Defaults to 64 MiB.
+func WithMemoryBudget(size int64) PathOption {
+	return func(c *pathConfig) {
+		c.budget = size
+	}
+}
+
+// Walk directory and serve its files, caching small files in memory and streaming large ones from disk, determine Content-Type based on file extension. Register route/<prefix>/<relative path without extension>, preserving subdirectories.
+// Index files are served at their directory URL. Routes that collide with each other or with existing routes are reported and nothing is registered.
+func (f *Fx) AddPath(dir string, prefix string, options ...PathOption) error {
+	return f.addFS(os.DirFS(dir), dir, prefix, options...)
+}
+
+// AddFS registers the files of fsys, such as a go:embed filesystem, with the same routing as AddPath.
+func (f *Fx) AddFS(fsys fs.FS, prefix string, options ...PathOption) error {
+	return f.addFS(fsys, "", prefix, options...)
+}
+
+// addFS walks fsys from its root, root names the filesystem in errors.
+func (f *Fx) addFS(fsys fs.FS, root string, prefix string, options ...PathOption) error {
+	config := &pathConfig{
+		index:       []string{"index.html", "index.htm"},
+		streamAbove: 4 << 20,
+	}
+	for _, option := range options {
+		option(config)
+	}
+	if config.budget > 0 {
+		f.content.resize(config.budget)
+	}
+	files := make(map[string]*asset)
+	var order []string
+	var collisions []error
+
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
 			return err
 		}
 
-		fileData, err := os.ReadFile(path)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		contentType, err := f.sniffType(fsys, p)
 		if err != nil {
 			return err
 		}
 
-		base := filepath.Base(path)
-		name := base[:len(base)-len(filepath.Ext(base))]
-		contentType := f.getType(base, fileData)
-		routePath := "/" + strings.Trim(prefix, "/") + "/" + name
+		source := path.Join(filepath.ToSlash(root), p)
+		file := &asset{
+			fsys:        fsys,
+			name:        p,
+			source:      source,
+			size:        info.Size(),
+			modTime:     info.ModTime(),
+			contentType: contentType,
+			stream:      info.Size() > config.streamAbove,
+			cache:       f.content,
+		}
+		// Sources of separate AddFS calls can be equal, the asset itself identifies the cached content.
+		file.key = fmt.Sprintf("%p", file)
+		for _, routePath := range config.routes(prefix, p) {
+			if other, exists := files[routePath]; exists {
+				collisions = append(collisions, fmt.Errorf("route %s: %s collides with %s", routePath, source, other.source))
+				continue
+			}
+			if other, exists := f.routeSource(routePath); exists {
+				collisions = append(collisions, fmt.Errorf("route %s: %s collides with %s", routePath, source, other))
+				continue
+			}
+			files[routePath] = file
+			order = append(order, routePath)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to walk %s: %w", path.Join(filepath.ToSlash(root), "."), err)
+	}
+	if len(collisions) > 0 {
+		return errors.Join(collisions...)
+	}
 
-		f.addRoute(routePath, fileData, contentType)
+	for _, routePath := range order {
+		file := files[routePath]
+		f.paths[routePath] = file.source
+		f.addRoute(routePath, file)
+	}
+	return nil
+}
+
+// routes returns the route paths for a slash separated path relative to the walked directory.
+func (c *pathConfig) routes(prefix, rel string) []string {
+	base := "/" + strings.Trim(prefix, "/")
+	dir, name := path.Split(rel)
+
+	if slices.Contains(c.index, name) {
+		dirPath := path.Join(base, dir)
+		if dirPath == "/" {
+			return []string{"/"}
+		}
+		return []string{dirPath, dirPath + "/"}
+	}
+
+	if !c.keepExt {
+		name = name[:len(name)-len(path.Ext(name))]
+	}
+	return []string{path.Join(base, dir, name)}
+}
+
+// routeSource reports what already serves routePath, either a file from a previous AddPath or a route registered on the router.
+func (f *Fx) routeSource(routePath string) (string, bool) {
+	if source, exists := f.paths[routePath]; exists {
+		return source, true
+	}
+	var source string
+	f.Zero.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if tmpl, err := route.GetPathTemplate(); err == nil && tmpl == routePath {
+			source = "registered route " + tmpl
+			return errors.New("found")
+		}
 		return nil
 	})
+	return source, source != ""
 }
 
-func (f *Fx) getType(filename string, data []byte) string {
-	contentType := mime.TypeByExtension(filepath.Ext(filename))
-	if contentType == "" {
-		contentType = http.DetectContentType(data)
+// sniffType determines the Content-Type from the file extension, falling back to the first 512 bytes of the file.
+func (f *Fx) sniffType(fsys fs.FS, name string) (string, error) {
+	if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+		return contentType, nil
 	}
-	return contentType
+	file, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return http.DetectContentType(head[:n]), nil
 }
 
-func (f *Fx) addRoute(path string, data []byte, contentType string) {
+func (f *Fx) addRoute(path string, file *asset) {
 	f.Zero.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", contentType)
-		w.Write(data)
+		w.Header().Set("Content-Type", file.contentType)
+		if err := file.serve(w, r); err != nil {
+			http.Error(w, "file unavailable", http.StatusInternalServerError)
+		}
 	})
 }
+
+// asset is a file registered by AddPath or AddFS.
+type asset struct {
+	fsys        fs.FS
+	name        string
+	source      string
+	size        int64
+	modTime     time.Time
+	contentType string
+	stream      bool
+	cache       *byteCache
+	key         string
+}
+
+// serve writes the file through http.ServeContent, which handles range and conditional requests.
+func (a *asset) serve(w http.ResponseWriter, r *http.Request) error {
+	if a.stream {
+		file, err := a.fsys.Open(a.name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if content, ok := file.(io.ReadSeeker); ok {
+			http.ServeContent(w, r, a.name, a.modTime, content)
+			return nil
+		}
+		w.Header().Set("Content-Length", strconv.FormatInt(a.size, 10))
+		if written, err := io.Copy(w, file); err != nil {
+			if written == 0 {
+				return err
+			}
+			// The status line is sent, the truncated body tells the client.
+			log.Printf("Failed to stream %s after %d bytes: %v", a.source, written, err)
+		}
+		return nil
+	}
+
+	data, ok := a.cache.get(a.key)
+	if !ok {
+		var err error
+		if data, err = fs.ReadFile(a.fsys, a.name); err != nil {
+			return err
+		}
+		a.cache.put(a.key, data, time.Time{})
+	}
+	http.ServeContent(w, r, a.name, a.modTime, bytes.NewReader(data))
+	return nil
+}
@@ -0,0 +1,267 @@
+package fx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Multicall3 is the address Multicall3 is deployed at on most chains.
+var Multicall3 = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+// multicallABI declares aggregate3 of Multicall3.
+const multicallABI = `[{"type":"function","name":"aggregate3","stateMutability":"payable",
+	"inputs":[{"name":"calls","type":"tuple[]","components":[
+		{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}]}],
+	"outputs":[{"name":"returnData","type":"tuple[]","components":[
+		{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}]}]}]`
+
+var multicall = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(multicallABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// Contract is a contract ABI for read-only calls through the active Ethereum endpoint.
+type Contract struct {
+	f   *Fx
+	abi abi.ABI
+}
+
+// LoadABI parses an ABI in JSON, either the bare array or a compiler artifact with an "abi" field.
+func (f *Fx) LoadABI(data []byte) (*Contract, error) {
+	var artifact struct {
+		ABI json.RawMessage `json:"abi"`
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &artifact); err != nil {
+			return nil, fmt.Errorf("failed to parse ABI artifact: %w", err)
+		}
+		data = artifact.ABI
+	}
+	parsed, err := abi.JSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+	return &Contract{f: f, abi: parsed}, nil
+}
+
+type blockKey struct{}
+
+// AtBlock pins the calls and multicalls made with the returned context to block number,
+// so reads across several calls come from one consistent state.
+func AtBlock(ctx context.Context, number uint64) context.Context {
+	return context.WithValue(ctx, blockKey{}, new(big.Int).SetUint64(number))
+}
+
+// PinLatest pins the returned context to the current head block, see AtBlock.
+func (f *Fx) PinLatest(ctx context.Context) (context.Context, error) {
+	_, eth, err := f.EthClients()
+	if err != nil {
+		return nil, err
+	}
+	number, err := eth.BlockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the head block: %w", err)
+	}
+	return AtBlock(ctx, number), nil
+}
+
+func blockOf(ctx context.Context) *big.Int {
+	number, _ := ctx.Value(blockKey{}).(*big.Int)
+	return number
+}
+
+// Call calls the view function method of the contract at address and returns its decoded outputs,
+// at the block pinned by AtBlock or PinLatest, otherwise the latest. Reverts are returned with their reason.
+func (c *Contract) Call(ctx context.Context, address common.Address, method string, args ...any) ([]any, error) {
+	data, err := c.call(ctx, address, method, args)
+	if err != nil {
+		return nil, err
+	}
+	values, err := c.abi.Unpack(method, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", method, err)
+	}
+	return values, nil
+}
+
+// CallAs calls method like Contract.Call and converts its output to T, a struct with a field per output for
+// methods returning several values.
+func CallAs[T any](ctx context.Context, c *Contract, address common.Address, method string, args ...any) (T, error) {
+	var result T
+	data, err := c.call(ctx, address, method, args)
+	if err != nil {
+		return result, err
+	}
+	if m, ok := c.abi.Methods[method]; ok && len(m.Outputs) > 1 {
+		if err := c.abi.UnpackIntoInterface(&result, method, data); err != nil {
+			return result, fmt.Errorf("failed to decode %s: %w", method, err)
+		}
+		return result, nil
+	}
+	values, err := c.abi.Unpack(method, data)
+	if err != nil {
+		return result, fmt.Errorf("failed to decode %s: %w", method, err)
+	}
+	if len(values) != 1 {
+		return result, fmt.Errorf("failed to decode %s: %d outputs", method, len(values))
+	}
+	return convertValue[T](method, values[0])
+}
+
+// convertValue converts a decoded value to T, which abi.ConvertType does by panicking on a mismatch.
+func convertValue[T any](method string, value any) (result T, err error) {
+	if typed, ok := value.(T); ok {
+		return typed, nil
+	}
+	defer func() {
+		if recover() != nil {
+			err = fmt.Errorf("failed to decode %s: %T is not convertible to %T", method, value, result)
+		}
+	}()
+	return *abi.ConvertType(value, new(T)).(*T), nil
+}
+
+// call packs method with args and executes it with eth_call.
+func (c *Contract) call(ctx context.Context, address common.Address, method string, args []any) ([]byte, error) {
+	input, err := c.abi.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", method, err)
+	}
+	data, err := c.f.ethCall(ctx, address, input)
+	if err != nil {
+		return nil, fmt.Errorf("call %s: %w", method, err)
+	}
+	return data, nil
+}
+
+// ethCall executes input against address at the block pinned in ctx.
+func (f *Fx) ethCall(ctx context.Context, address common.Address, input []byte) ([]byte, error) {
+	_, eth, err := f.EthClients()
+	if err != nil {
+		return nil, err
+	}
+	data, err := eth.CallContract(ctx, ethereum.CallMsg{To: &address, Data: input}, blockOf(ctx))
+	if err != nil {
+		return nil, revertError(err)
+	}
+	return data, nil
+}
+
+// ContractCall is one read of a Multicall. A failing call with AllowFailure is reported in its CallResult,
+// any other failing call fails the whole Multicall.
+type ContractCall struct {
+	Contract     *Contract
+	Address      common.Address
+	Method       string
+	Args         []any
+	AllowFailure bool
+}
+
+// CallResult is the outcome of one ContractCall.
+type CallResult struct {
+	Values []any
+	Err    error
+}
+
+// Multicall executes calls in a single eth_call through Multicall3's aggregate3, at the block pinned in ctx.
+func (f *Fx) Multicall(ctx context.Context, calls ...ContractCall) ([]CallResult, error) {
+	type call3 struct {
+		Target       common.Address
+		AllowFailure bool
+		CallData     []byte
+	}
+	packed := make([]call3, len(calls))
+	for i, call := range calls {
+		input, err := call.Contract.abi.Pack(call.Method, call.Args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode call %d, %s: %w", i, call.Method, err)
+		}
+		packed[i] = call3{Target: call.Address, AllowFailure: call.AllowFailure, CallData: input}
+	}
+	input, err := multicall.Pack("aggregate3", packed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode multicall: %w", err)
+	}
+
+	data, err := f.ethCall(ctx, Multicall3, input)
+	if err != nil {
+		return nil, fmt.Errorf("multicall: %w", err)
+	}
+	var returned []struct {
+		Success    bool
+		ReturnData []byte
+	}
+	if err := multicall.UnpackIntoInterface(&returned, "aggregate3", data); err != nil {
+		return nil, fmt.Errorf("failed to decode multicall: %w", err)
+	}
+	if len(returned) != len(calls) {
+		return nil, fmt.Errorf("multicall returned %d results for %d calls", len(returned), len(calls))
+	}
+
+	results := make([]CallResult, len(calls))
+	for i, call := range calls {
+		if !returned[i].Success {
+			results[i].Err = fmt.Errorf("call %s: %w", call.Method, &RevertError{Reason: revertReason(returned[i].ReturnData), Data: returned[i].ReturnData})
+			continue
+		}
+		values, err := call.Contract.abi.Unpack(call.Method, returned[i].ReturnData)
+		if err != nil {
+			results[i].Err = fmt.Errorf("failed to decode %s: %w", call.Method, err)
+			continue
+		}
+		results[i].Values = values
+	}
+	return results, nil
+}
+
+// RevertError is a call the contract reverted, with the reason from Error(string) or Panic(uint256) when it gave one.
+type RevertError struct {
+	Reason string
+	Data   []byte
+}
+
+func (e *RevertError) Error() string {
+	if e.Reason == "" {
+		return "execution reverted"
+	}
+	return "execution reverted: " + e.Reason
+}
+
+// revertError turns a reverted eth_call into a RevertError, passing other errors through.
+func revertError(err error) error {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return err
+	}
+	encoded, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return err
+	}
+	data, decodeErr := hexutil.Decode(encoded)
+	if decodeErr != nil {
+		return err
+	}
+	return &RevertError{Reason: revertReason(data), Data: data}
+}
+
+func revertReason(data []byte) string {
+	reason, err := abi.UnpackRevert(data)
+	if err != nil {
+		return ""
+	}
+	return reason
+}
@@ -0,0 +1,99 @@
+package fx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// maxErrorBody is how much of a non-2xx response body an HTTPError keeps.
+const maxErrorBody = 4 << 10
+
+// HTTPError is returned when an upstream answers with a non-2xx status. Retrieve it with errors.As.
+type HTTPError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Status     string
+	Header     http.Header
+	Body       []byte // at most the first 4 KiB of the response body
+	Truncated  bool   // the response body was longer than Body
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%s %s: HTTP %d: %s", e.Method, e.URL, e.StatusCode, e.Status)
+}
+
+// newHTTPError reads a bounded snippet of the response body into an HTTPError.
+func newHTTPError(req *http.Request, resp *http.Response) *HTTPError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody+1))
+	truncated := len(body) > maxErrorBody
+	if truncated {
+		body = body[:maxErrorBody]
+	}
+	return &HTTPError{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+		Truncated:  truncated,
+	}
+}
+
+// ErrorDecoder turns an upstream error response into a domain error, or returns nil to keep the HTTPError.
+type ErrorDecoder func(*HTTPError) error
+
+// OnError registers decoder for non-2xx responses from host. The decoded error is returned with the HTTPError
+// still reachable through errors.As.
+func (f *Fx) OnError(host string, decoder ErrorDecoder) {
+	f.errs.Lock()
+	defer f.errs.Unlock()
+	f.errs.decoders[host] = decoder
+}
+
+// errorDecoders holds one ErrorDecoder per host.
+type errorDecoders struct {
+	sync.RWMutex
+	decoders map[string]ErrorDecoder
+}
+
+func newErrorDecoders() *errorDecoders {
+	return &errorDecoders{decoders: make(map[string]ErrorDecoder)}
+}
+
+// decode applies the decoder registered for host when err carries an HTTPError.
+func (d *errorDecoders) decode(host string, err error) error {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		return err
+	}
+
+	d.RLock()
+	decoder, exists := d.decoders[host]
+	d.RUnlock()
+	if !exists {
+		return err
+	}
+	if domain := decoder(httpErr); domain != nil {
+		return &decodedError{domain: domain, http: httpErr}
+	}
+	return err
+}
+
+// decodedError reports the domain error while unwrapping to both it and the HTTPError.
+type decodedError struct {
+	domain error
+	http   *HTTPError
+}
+
+func (e *decodedError) Error() string {
+	return e.domain.Error()
+}
+
+func (e *decodedError) Unwrap() []error {
+	return []error{e.domain, e.http}
+}
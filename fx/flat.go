@@ -0,0 +1,462 @@
+package fx
+
+import (
+	"cmp"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// SimplifyOption configures how Simplify flattens and Unflatten rebuilds keys.
+type SimplifyOption func(*flatConfig)
+
+// ArrayNotation selects how array indexes appear in flattened keys.
+type ArrayNotation int
+
+const (
+	// ArrayBrackets writes indexes as a[0].b
+	ArrayBrackets ArrayNotation = iota
+	// ArrayDots writes indexes as a.0.b, using the key separator
+	ArrayDots
+)
+
+type flatConfig struct {
+	separator string
+	notation  ArrayNotation
+	keepEmpty bool
+	maxDepth  int
+	sorted    bool
+}
+
+func newFlatConfig(options []SimplifyOption) *flatConfig {
+	config := &flatConfig{separator: "."}
+	for _, option := range options {
+		option(config)
+	}
+	return config
+}
+
+// WithSeparator joins nested keys with separator instead of ".".
+func WithSeparator(separator string) SimplifyOption {
+	return func(c *flatConfig) {
+		if separator != "" {
+			c.separator = separator
+		}
+	}
+}
+
+// WithArrayNotation selects bracket (a[0]) or dotted (a.0) array indexes.
+func WithArrayNotation(notation ArrayNotation) SimplifyOption {
+	return func(c *flatConfig) {
+		c.notation = notation
+	}
+}
+
+// WithKeepEmpty keeps nil values, empty strings, empty maps and empty arrays.
+func WithKeepEmpty() SimplifyOption {
+	return func(c *flatConfig) {
+		c.keepEmpty = true
+	}
+}
+
+// WithMaxDepth stops flattening after depth levels and keeps deeper values nested under their key.
+func WithMaxDepth(depth int) SimplifyOption {
+	return func(c *flatConfig) {
+		c.maxDepth = depth
+	}
+}
+
+// WithSorted makes Simplify return Fields ordered by key, with array indexes in numeric order.
+func WithSorted() SimplifyOption {
+	return func(c *flatConfig) {
+		c.sorted = true
+	}
+}
+
+// Field is one flattened key and its value.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Fields is sorted Simplify output. It encodes as a JSON object that keeps the order.
+type Fields []Field
+
+func (fs Fields) MarshalJSON() ([]byte, error) {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, field := range fs {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, err := json.Marshal(field.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Key, err)
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return []byte(b.String()), nil
+}
+
+// Map returns the fields as a map.
+func (fs Fields) Map() map[string]any {
+	result := make(map[string]any, len(fs))
+	for _, field := range fs {
+		result[field.Key] = field.Value
+	}
+	return result
+}
+
+// Unflatten rebuilds nested maps and arrays from Simplify output, either a map[string]any or Fields.
+// Pass the options used to flatten so keys split the same way. Missing array elements become nil.
+func (f *Fx) Unflatten(flat any, options ...SimplifyOption) (any, error) {
+	config := newFlatConfig(options)
+	var values map[string]any
+	switch v := flat.(type) {
+	case nil:
+		return nil, nil
+	case map[string]any:
+		values = v
+	case Fields:
+		values = v.Map()
+	default:
+		return nil, fmt.Errorf("cannot unflatten %T, want map[string]any or Fields", flat)
+	}
+
+	var root any
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		var err error
+		if root, err = insert(root, config.split(key), values[key], key); err != nil {
+			return nil, err
+		}
+	}
+	return root, nil
+}
+
+// maxIndex bounds array indexes in Unflatten keys so a single key cannot allocate a huge array.
+const maxIndex = 1 << 20
+
+// insert places value at segments below node and returns the updated node.
+func insert(node any, segments []keySegment, value any, key string) (any, error) {
+	if len(segments) == 0 {
+		if node != nil {
+			return nil, fmt.Errorf("key %s conflicts with a nested key", key)
+		}
+		return value, nil
+	}
+
+	segment := segments[0]
+	if segment.isIndex {
+		array, ok := node.([]any)
+		if node != nil && !ok {
+			return nil, fmt.Errorf("key %s indexes a value that is not an array", key)
+		}
+		if segment.index >= maxIndex {
+			return nil, fmt.Errorf("key %s: index %d exceeds %d", key, segment.index, maxIndex)
+		}
+		for len(array) <= segment.index {
+			array = append(array, nil)
+		}
+		child, err := insert(array[segment.index], segments[1:], value, key)
+		if err != nil {
+			return nil, err
+		}
+		array[segment.index] = child
+		return array, nil
+	}
+
+	object, ok := node.(map[string]any)
+	if node != nil && !ok {
+		return nil, fmt.Errorf("key %s nests under a value that is not an object", key)
+	}
+	if object == nil {
+		object = make(map[string]any)
+	}
+	child, err := insert(object[segment.name], segments[1:], value, key)
+	if err != nil {
+		return nil, err
+	}
+	object[segment.name] = child
+	return object, nil
+}
+
+func (c *flatConfig) buildKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + c.separator + key
+}
+
+func (c *flatConfig) buildArrayKey(prefix string, index int) string {
+	if c.notation == ArrayDots {
+		return c.buildKey(prefix, strconv.Itoa(index))
+	}
+	arrayKey := fmt.Sprintf("[%d]", index)
+	if prefix == "" {
+		return arrayKey
+	}
+	return prefix + arrayKey
+}
+
+// sortFields orders the flattened result by key segments, comparing array indexes numerically.
+func (c *flatConfig) sortFields(result map[string]any) Fields {
+	fields := make(Fields, 0, len(result))
+	segments := make(map[string][]keySegment, len(result))
+	for key, value := range result {
+		fields = append(fields, Field{Key: key, Value: value})
+		segments[key] = c.split(key)
+	}
+	slices.SortFunc(fields, func(a, b Field) int {
+		return slices.CompareFunc(segments[a.Key], segments[b.Key], compareSegments)
+	})
+	return fields
+}
+
+// compareSegments orders indexes before names, indexes numerically and names lexically.
+func compareSegments(a, b keySegment) int {
+	switch {
+	case a.isIndex && b.isIndex:
+		return cmp.Compare(a.index, b.index)
+	case a.isIndex:
+		return -1
+	case b.isIndex:
+		return 1
+	default:
+		return strings.Compare(a.name, b.name)
+	}
+}
+
+type keySegment struct {
+	name    string
+	index   int
+	isIndex bool
+}
+
+// splitKey splits a key in the default Simplify notation into its map keys and array indexes.
+func splitKey(key string) []keySegment {
+	return (&flatConfig{separator: "."}).split(key)
+}
+
+// split splits a flattened key into its map keys and array indexes.
+func (c *flatConfig) split(key string) []keySegment {
+	var segments []keySegment
+	for part := range strings.SplitSeq(key, c.separator) {
+		if c.notation == ArrayDots {
+			if index, err := strconv.Atoi(part); err == nil && index >= 0 {
+				segments = append(segments, keySegment{index: index, isIndex: true})
+				continue
+			}
+			segments = append(segments, keySegment{name: part})
+			continue
+		}
+		segments = append(segments, splitPart(part)...)
+	}
+	return segments
+}
+
+// splitPart splits "name[0][1]" into its name and indexes, treating malformed brackets as part of the name.
+func splitPart(part string) []keySegment {
+	var segments []keySegment
+	name, rest, _ := strings.Cut(part, "[")
+	if name != "" {
+		segments = append(segments, keySegment{name: name})
+	}
+	for rest != "" {
+		digits, after, found := strings.Cut(rest, "]")
+		index, err := strconv.Atoi(digits)
+		if !found || err != nil || index < 0 || (after != "" && after[0] != '[') {
+			return []keySegment{{name: part}}
+		}
+		segments = append(segments, keySegment{index: index, isIndex: true})
+		rest = strings.TrimPrefix(after, "[")
+	}
+	return segments
+}
+
+var (
+	jsonMarshaler = reflect.TypeFor[json.Marshaler]()
+	textMarshaler = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// normalize converts structs, typed maps and slices into map[string]any and []any, following json tags.
+// Values that marshal themselves, such as time.Time or common.Address, are kept as they are.
+func normalize(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Pointer && marshals(v) {
+			return v.Interface()
+		}
+		return normalize(v.Elem())
+	}
+	if marshals(v) {
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		result := make(map[string]any)
+		normalizeStruct(v, result)
+		return result
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		result := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			result[fmt.Sprint(leaf(iter.Key()))] = normalize(iter.Value())
+		}
+		return result
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return leaf(v)
+		}
+		result := make([]any, v.Len())
+		for i := range result {
+			result[i] = normalize(v.Index(i))
+		}
+		return result
+	default:
+		return leaf(v)
+	}
+}
+
+// marshals reports whether v encodes itself and can be handed out as is.
+func marshals(v reflect.Value) bool {
+	return v.CanInterface() && (v.Type().Implements(jsonMarshaler) || v.Type().Implements(textMarshaler))
+}
+
+// leaf returns a scalar, reading it by kind when it was reached through an unexported embedded struct.
+func leaf(v reflect.Value) any {
+	if v.CanInterface() {
+		return v.Interface()
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Bytes()
+		}
+	}
+	return nil
+}
+
+// normalizeStruct copies the exported fields of v into result under their json names, inlining embedded structs.
+// Names claimed by several fields follow encoding/json: the shallowest field wins, a json tag breaks a tie at the
+// same depth, and otherwise the name is left out.
+func normalizeStruct(v reflect.Value, result map[string]any) {
+	var fields []structField
+	collectFields(v, 0, &fields)
+
+	byName := make(map[string][]structField)
+	for _, field := range fields {
+		byName[field.name] = append(byName[field.name], field)
+	}
+	for name, candidates := range byName {
+		if field, ok := dominantField(candidates); ok {
+			result[name] = normalize(field.value)
+		}
+	}
+}
+
+// structField is a field of a struct or of a struct embedded in it, depth levels down.
+type structField struct {
+	name   string
+	depth  int
+	tagged bool
+	value  reflect.Value
+}
+
+// collectFields appends the exported fields of v under their json names, and those of its embedded structs one level deeper.
+func collectFields(v reflect.Value, depth int, fields *[]structField) {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		value := v.Field(i)
+		if field.Anonymous && name == "" {
+			embedded := value
+			if embedded.Kind() == reflect.Pointer {
+				if embedded.IsNil() {
+					continue
+				}
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct && !marshals(embedded) {
+				collectFields(embedded, depth+1, fields)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		tagged := name != ""
+		if !tagged {
+			name = field.Name
+		}
+		*fields = append(*fields, structField{name: name, depth: depth, tagged: tagged, value: value})
+	}
+}
+
+// dominantField picks the field encoding/json keeps among those sharing a name.
+func dominantField(fields []structField) (structField, bool) {
+	depth := fields[0].depth
+	for _, field := range fields[1:] {
+		depth = min(depth, field.depth)
+	}
+	var shallowest []structField
+	for _, field := range fields {
+		if field.depth == depth {
+			shallowest = append(shallowest, field)
+		}
+	}
+	if len(shallowest) == 1 {
+		return shallowest[0], true
+	}
+	var tagged []structField
+	for _, field := range shallowest {
+		if field.tagged {
+			tagged = append(tagged, field)
+		}
+	}
+	if len(tagged) == 1 {
+		return tagged[0], true
+	}
+	return structField{}, false
+}
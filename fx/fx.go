@@ -2,10 +2,12 @@ package fx
 
 import (
 	"database/sql"
+	"fmt"
 	"net/http"
+	"sync"
+	"sync/atomic"
 
-	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
 	"github.com/redis/go-redis/v9"
 
@@ -13,16 +15,48 @@ import (
 )
 
 type Fx struct {
-	Rpc      *rpc.Client
-	Eth      *ethclient.Client
-	postgres *sql.DB
-	redis    *redis.Client
-	Http     *http.Client
+	postgres   *sql.DB
+	redis      *redis.Client
+	Http       *http.Client
+	paths      map[string]string
+	content    *byteCache
+	limits     *hostLimits
+	errs       *errorDecoders
+	cache      CacheStore
+	operations map[*mux.Route]*operation
+	node       atomic.Pointer[node]
+	proxy      *proxy
+	feeds      *feeds
+	headsOnce  sync.Once
 	*zero.Zero
 }
 
+// Init builds an Fx with the default Http client.
 func Init() *Fx {
+	client, _ := NewClient() // the defaults are always valid
+	return newFx(client)
+}
+
+// InitWithClient builds an Fx with an Http client configured by options, failing when an option is invalid,
+// such as an unreadable CA bundle.
+func InitWithClient(options ...ClientOption) (*Fx, error) {
+	client, err := NewClient(options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure the Http client: %w", err)
+	}
+	return newFx(client), nil
+}
+
+func newFx(client *http.Client) *Fx {
 	return &Fx{
-		Zero: zero.NewZero(),
+		Http:       client,
+		Zero:       zero.NewZero(),
+		paths:      make(map[string]string),
+		content:    newByteCache(64 << 20),
+		limits:     newHostLimits(),
+		errs:       newErrorDecoders(),
+		proxy:      newProxy(DefaultProxyConfig()),
+		feeds:      newFeeds(),
+		operations: make(map[*mux.Route]*operation),
 	}
 }
@@ -0,0 +1,439 @@
+package fx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ProxyConfig configures the JSON-RPC proxy served by GethHandler.
+type ProxyConfig struct {
+	Allow            []string      // method patterns such as "eth_*", empty allows every method that is not denied
+	Deny             []string      // method patterns refused even when allowed
+	Origins          []string      // CORS and WebSocket origins, "*" for any, empty allows the same host only
+	RateLimit        float64       // requests per second per client, 0 disables limiting
+	Burst            int           // requests a client may send at once
+	MaxBody          int64         // request body limit in bytes
+	MaxBatch         int           // calls allowed in one batch, 0 for no limit
+	MaxSubscriptions int           // subscriptions one WebSocket client may hold, 0 for no limit
+	Timeout          time.Duration // upstream call timeout
+	TrustProxies     bool          // identify clients by the last X-Forwarded-For entry added by a reverse proxy
+	Cache            CacheStore    // stores results of immutable methods once final, such as NewMemoryStore or RedisStore, nil disables caching
+	CacheTTL         time.Duration // how long cached results are kept, 0 keeps them until the store evicts them
+}
+
+// DefaultProxyConfig allows the read-only eth, net and web3 namespaces, refuses signing and account methods,
+// allows only same-host browser origins and limits each client to 10 requests per second.
+func DefaultProxyConfig() ProxyConfig {
+	return ProxyConfig{
+		Allow: []string{"eth_*", "net_*", "web3_*"},
+		Deny: []string{
+			"admin_*", "personal_*", "debug_*", "miner_*", "engine_*",
+			"eth_sign", "eth_signTransaction", "eth_signTypedData*", "eth_sendTransaction", "eth_accounts",
+		},
+		RateLimit:        10,
+		Burst:            20,
+		MaxBody:          1 << 20,
+		MaxBatch:         20,
+		MaxSubscriptions: 16,
+		Timeout:          30 * time.Second,
+	}
+}
+
+// ConfigureProxy replaces the configuration used by GethHandler.
+func (f *Fx) ConfigureProxy(config ProxyConfig) {
+	f.proxy = newProxy(config)
+}
+
+// proxy holds the GethHandler configuration and its per-client rate limits.
+type proxy struct {
+	config  ProxyConfig
+	clients *hostLimits
+	pruned  atomic.Int64
+	cache   *rpcCache
+	hits    atomic.Uint64
+	misses  atomic.Uint64
+}
+
+func newProxy(config ProxyConfig) *proxy {
+	p := &proxy{config: config, clients: newHostLimits()}
+	if config.Cache != nil {
+		p.cache = &rpcCache{store: config.Cache, ttl: config.CacheTTL}
+	}
+	p.pruned.Store(time.Now().UnixNano())
+	return p
+}
+
+// JSON-RPC 2.0 error codes.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+	rpcServerError    = -32000
+	rpcLimitExceeded  = -32005
+)
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// GethHandler proxies JSON-RPC 2.0 requests, single calls and batches, to the active Ethereum endpoint, applying the method allow and deny lists,
+// per-client rate limits, the body size limit and CORS origins from ConfigureProxy or DefaultProxyConfig.
+func (f *Fx) GethHandler(w http.ResponseWriter, r *http.Request) {
+	p := f.proxy
+	p.cors(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST, OPTIONS")
+		writeRPCError(w, http.StatusMethodNotAllowed, nil, rpcInvalidRequest, "JSON-RPC requests must use POST")
+		return
+	}
+	if !p.allowClient(r, 1) {
+		writeRPCError(w, http.StatusTooManyRequests, nil, rpcLimitExceeded, "rate limit exceeded")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, p.config.MaxBody))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeRPCError(w, http.StatusRequestEntityTooLarge, nil, rpcLimitExceeded, "request body too large")
+			return
+		}
+		writeRPCError(w, http.StatusBadRequest, nil, rpcParseError, "failed to read request")
+		return
+	}
+
+	calls, batch, failure := parseCalls(body)
+	if failure != nil {
+		writeRPCResponse(w, http.StatusOK, rpcResponse{Error: failure})
+		return
+	}
+	if batch && p.config.MaxBatch > 0 && len(calls) > p.config.MaxBatch {
+		writeRPCError(w, http.StatusOK, nil, rpcLimitExceeded, fmt.Sprintf("batch exceeds %d calls", p.config.MaxBatch))
+		return
+	}
+	// The first call of a batch was paid for before reading the body.
+	if !p.allowClient(r, len(calls)-1) {
+		writeRPCError(w, http.StatusTooManyRequests, nil, rpcLimitExceeded, "rate limit exceeded")
+		return
+	}
+	p.checkMethods(calls)
+
+	client, _, err := f.EthClients()
+	if err != nil {
+		for _, call := range calls {
+			if call.err == nil {
+				call.err = &rpcError{Code: rpcServerError, Message: "ethereum client unavailable"}
+			}
+		}
+		if !batch {
+			writeRPCResponse(w, http.StatusServiceUnavailable, calls[0].response())
+			return
+		}
+	} else {
+		ctx, cancel := context.WithTimeout(r.Context(), p.config.Timeout)
+		defer cancel()
+		p.forward(ctx, client, f.chainID(), calls)
+	}
+
+	// A batch of only notifications gets no body at all.
+	responses := replies(calls)
+	switch {
+	case len(responses) == 0:
+		w.WriteHeader(http.StatusNoContent)
+	case batch:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(responses)
+	default:
+		writeRPCResponse(w, http.StatusOK, responses[0])
+	}
+}
+
+// rpcCall is one call of a request, carrying its result or error once forwarded.
+type rpcCall struct {
+	id           json.RawMessage
+	notification bool
+	method       string
+	params       []any
+	result       json.RawMessage
+	cached       bool
+	err          *rpcError
+}
+
+// replies returns the responses to calls, leaving out notifications.
+func replies(calls []*rpcCall) []rpcResponse {
+	var responses []rpcResponse
+	for _, call := range calls {
+		if !call.notification {
+			responses = append(responses, call.response())
+		}
+	}
+	return responses
+}
+
+func (c *rpcCall) response() rpcResponse {
+	if c.err != nil {
+		return rpcResponse{ID: c.id, Error: c.err}
+	}
+	return rpcResponse{ID: c.id, Result: c.result}
+}
+
+// parseCalls decodes a single call or a batch, reporting a body that is not JSON or an empty batch as a request-level error.
+func parseCalls(body []byte) ([]*rpcCall, bool, *rpcError) {
+	body = bytes.TrimSpace(body)
+	if !json.Valid(body) {
+		return nil, false, &rpcError{Code: rpcParseError, Message: "parse error"}
+	}
+	if len(body) == 0 || body[0] != '[' {
+		return []*rpcCall{parseCall(body)}, false, nil
+	}
+
+	var raw []json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, true, &rpcError{Code: rpcParseError, Message: "parse error"}
+	}
+	if len(raw) == 0 {
+		return nil, true, &rpcError{Code: rpcInvalidRequest, Message: "empty batch"}
+	}
+	calls := make([]*rpcCall, len(raw))
+	for i, message := range raw {
+		calls[i] = parseCall(message)
+	}
+	return calls, true, nil
+}
+
+// parseCall validates one JSON-RPC 2.0 request object. Params must be positional, as go-ethereum does not accept named ones.
+func parseCall(message json.RawMessage) *rpcCall {
+	var req struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Method  string          `json:"method"`
+		Params  json.RawMessage `json:"params"`
+	}
+	invalid := &rpcCall{err: &rpcError{Code: rpcInvalidRequest, Message: "invalid request"}}
+	if err := json.Unmarshal(message, &req); err != nil {
+		return invalid
+	}
+	if req.ID != nil && !validID(req.ID) {
+		return invalid
+	}
+
+	// Invalid requests are answered even without an id.
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		invalid.id = req.ID
+		return invalid
+	}
+	call := &rpcCall{id: req.ID, notification: req.ID == nil, method: req.Method}
+	switch {
+	case req.Params == nil, string(req.Params) == "null":
+	case req.Params[0] == '[':
+		var params []json.RawMessage
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			call.err = &rpcError{Code: rpcInvalidParams, Message: "invalid params"}
+			return call
+		}
+		for _, param := range params {
+			call.params = append(call.params, param)
+		}
+	default:
+		call.err = &rpcError{Code: rpcInvalidParams, Message: "params must be an array"}
+	}
+	return call
+}
+
+// validID reports whether id is a string, a number or null, as the specification requires.
+func validID(id json.RawMessage) bool {
+	switch id[0] {
+	case '"', 'n', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return true
+	}
+	return false
+}
+
+// forward answers the calls without an error from the cache and sends the rest upstream,
+// a lone call with CallContext and several with BatchCallContext.
+func (p *proxy) forward(ctx context.Context, client *rpc.Client, chainID string, calls []*rpcCall) {
+	var valid []*rpcCall
+	for _, call := range calls {
+		if call.err == nil {
+			valid = append(valid, call)
+		}
+	}
+	if p.cache != nil {
+		p.lookup(ctx, chainID, valid)
+		defer p.remember(ctx, client, chainID, valid)
+	}
+
+	var pending []*rpcCall
+	for _, call := range valid {
+		if !call.cached {
+			pending = append(pending, call)
+		}
+	}
+
+	switch len(pending) {
+	case 0:
+		return
+	case 1:
+		call := pending[0]
+		if err := client.CallContext(ctx, &call.result, call.method, call.params...); err != nil {
+			call.err = upstreamError(err)
+		}
+		return
+	}
+
+	elems := make([]rpc.BatchElem, len(pending))
+	for i, call := range pending {
+		elems[i] = rpc.BatchElem{Method: call.method, Args: call.params, Result: &call.result}
+	}
+	err := client.BatchCallContext(ctx, elems)
+	for i, call := range pending {
+		if err != nil {
+			call.err = upstreamError(err)
+		} else if elems[i].Error != nil {
+			call.err = upstreamError(elems[i].Error)
+		}
+	}
+}
+
+// checkMethods fails the calls to methods the allow and deny lists refuse.
+func (p *proxy) checkMethods(calls []*rpcCall) {
+	for _, call := range calls {
+		if call.err == nil && !p.allowMethod(call.method) {
+			call.err = &rpcError{Code: rpcMethodNotFound, Message: "method " + call.method + " is not available"}
+		}
+	}
+}
+
+// allowMethod reports whether method matches the allow list, or the list is empty, and matches no deny pattern.
+func (p *proxy) allowMethod(method string) bool {
+	matches := func(pattern string) bool {
+		ok, _ := path.Match(pattern, method)
+		return ok
+	}
+	if slices.ContainsFunc(p.config.Deny, matches) {
+		return false
+	}
+	return len(p.config.Allow) == 0 || slices.ContainsFunc(p.config.Allow, matches)
+}
+
+// allowClient takes n tokens from the requesting client's bucket.
+func (p *proxy) allowClient(r *http.Request, n int) bool {
+	if p.config.RateLimit <= 0 || n <= 0 {
+		return true
+	}
+	// Buckets idle long enough to refill completely are dropped, which forgets nothing.
+	idle := max(time.Minute, time.Duration(float64(max(p.config.Burst, 1))/p.config.RateLimit*float64(time.Second)))
+	if last := p.pruned.Load(); time.Since(time.Unix(0, last)) > idle && p.pruned.CompareAndSwap(last, time.Now().UnixNano()) {
+		p.clients.prune(idle)
+	}
+	b := p.clients.forRequest(p.clientID(r), &requestSettings{limit: &rateLimit{perSecond: p.config.RateLimit, burst: p.config.Burst}})
+	return b.take(float64(n))
+}
+
+// clientID identifies the client by remote address, or by the address the reverse proxy saw when trusted.
+func (p *proxy) clientID(r *http.Request) string {
+	if p.config.TrustProxies {
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			hops := strings.Split(forwarded[len(forwarded)-1], ",")
+			if last := strings.TrimSpace(hops[len(hops)-1]); last != "" {
+				return last
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// cors sets the CORS headers when the request origin is allowed.
+func (p *proxy) cors(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "" || len(p.config.Origins) == 0 || !p.allowOrigin(origin) {
+		return
+	}
+	if slices.Contains(p.config.Origins, "*") {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+	}
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+}
+
+// upstreamError passes the node's JSON-RPC errors, such as reverts, through to the client and hides transport failures.
+func upstreamError(err error) *rpcError {
+	var callErr rpc.Error
+	if errors.As(err, &callErr) {
+		e := &rpcError{Code: callErr.ErrorCode(), Message: callErr.Error()}
+		var dataErr rpc.DataError
+		if errors.As(err, &dataErr) {
+			e.Data = dataErr.ErrorData()
+		}
+		return e
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &rpcError{Code: rpcServerError, Message: "upstream call timed out"}
+	}
+	log.Printf("JSON-RPC proxy upstream call failed: %v", err)
+	return &rpcError{Code: rpcInternalError, Message: "upstream call failed"}
+}
+
+// MarshalJSON fills in the version, a null id for requests whose id could not be read and a null result.
+func (r rpcResponse) MarshalJSON() ([]byte, error) {
+	type response rpcResponse
+	r.JSONRPC = "2.0"
+	if r.ID == nil {
+		r.ID = json.RawMessage("null")
+	}
+	if r.Error == nil && r.Result == nil {
+		r.Result = json.RawMessage("null")
+	}
+	return json.Marshal(response(r))
+}
+
+// allowOrigin reports whether origin is one of the configured origins.
+func (p *proxy) allowOrigin(origin string) bool {
+	return slices.Contains(p.config.Origins, "*") || slices.Contains(p.config.Origins, origin)
+}
+
+func writeRPCError(w http.ResponseWriter, status int, id json.RawMessage, code int, message string) {
+	writeRPCResponse(w, status, rpcResponse{ID: id, Error: &rpcError{Code: code, Message: message}})
+}
+
+func writeRPCResponse(w http.ResponseWriter, status int, resp rpcResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,460 @@
+package fx
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// IndexOption configures Index.
+type IndexOption func(*indexConfig)
+
+type indexConfig struct {
+	start      *uint64 // nil starts at the head
+	addresses  []common.Address
+	topics     [][]common.Hash
+	poll       time.Duration
+	reorgDepth uint64
+}
+
+// WithStartBlock sets the first block indexed when there is no checkpoint yet. Defaults to the current head.
+func WithStartBlock(number uint64) IndexOption {
+	return func(c *indexConfig) {
+		c.start = &number
+	}
+}
+
+// WithLogFilter indexes only the logs emitted by addresses and matching topics, in the positional format of eth_getLogs.
+// Defaults to every log.
+func WithLogFilter(addresses []common.Address, topics [][]common.Hash) IndexOption {
+	return func(c *indexConfig) {
+		c.addresses = addresses
+		c.topics = topics
+	}
+}
+
+// WithPollInterval sets how often the head is polled when the endpoint cannot push new heads. Defaults to 12s.
+func WithPollInterval(interval time.Duration) IndexOption {
+	return func(c *indexConfig) {
+		c.poll = interval
+	}
+}
+
+// WithReorgDepth sets how many blocks a reorg may roll back before Index gives up. Defaults to 64.
+func WithReorgDepth(depth uint64) IndexOption {
+	return func(c *indexConfig) {
+		c.reorgDepth = depth
+	}
+}
+
+// indexMigrations create the indexer tables, each applied once in order and recorded in eth_migrations.
+var indexMigrations = []string{
+	`CREATE TABLE eth_blocks (
+		number      BIGINT PRIMARY KEY,
+		hash        BYTEA NOT NULL UNIQUE,
+		parent_hash BYTEA NOT NULL,
+		time        TIMESTAMPTZ NOT NULL,
+		miner       BYTEA NOT NULL,
+		gas_used    BIGINT NOT NULL,
+		gas_limit   BIGINT NOT NULL,
+		base_fee    NUMERIC,
+		tx_count    INTEGER NOT NULL
+	);
+	CREATE TABLE eth_transactions (
+		hash         BYTEA PRIMARY KEY,
+		block_number BIGINT NOT NULL REFERENCES eth_blocks (number) ON DELETE CASCADE,
+		tx_index     INTEGER NOT NULL,
+		from_address BYTEA,
+		to_address   BYTEA,
+		value        NUMERIC NOT NULL,
+		nonce        BIGINT NOT NULL,
+		gas          BIGINT NOT NULL,
+		gas_price    NUMERIC,
+		input        BYTEA NOT NULL
+	);
+	CREATE INDEX eth_transactions_block ON eth_transactions (block_number);
+	CREATE INDEX eth_transactions_from ON eth_transactions (from_address);
+	CREATE INDEX eth_transactions_to ON eth_transactions (to_address);
+	CREATE TABLE eth_logs (
+		block_number BIGINT NOT NULL REFERENCES eth_blocks (number) ON DELETE CASCADE,
+		log_index    INTEGER NOT NULL,
+		tx_hash      BYTEA NOT NULL,
+		address      BYTEA NOT NULL,
+		topic0       BYTEA,
+		topic1       BYTEA,
+		topic2       BYTEA,
+		topic3       BYTEA,
+		data         BYTEA NOT NULL,
+		PRIMARY KEY (block_number, log_index)
+	);
+	CREATE INDEX eth_logs_address_topic0 ON eth_logs (address, topic0);
+	CREATE TABLE eth_checkpoint (
+		id           BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+		block_number BIGINT NOT NULL,
+		block_hash   BYTEA NOT NULL,
+		updated_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`,
+	// gas_price holds the price paid, max_fee and max_priority_fee the caps of EIP-1559 transactions.
+	`ALTER TABLE eth_transactions ADD COLUMN max_fee NUMERIC, ADD COLUMN max_priority_fee NUMERIC`,
+}
+
+// Index follows the chain into the Postgres database from ConnectPostgres, writing each block, its transactions and
+// the logs matching the filter in one transaction together with a checkpoint, so a restart resumes after the last
+// indexed block. When a block does not extend the stored chain the orphaned blocks are deleted and indexing continues
+// from the fork point. Index runs until ctx ends and returns early only on migration failures or too deep reorgs,
+// logging and retrying other errors.
+func (f *Fx) Index(ctx context.Context, options ...IndexOption) error {
+	config := &indexConfig{poll: 12 * time.Second, reorgDepth: 64}
+	for _, option := range options {
+		option(config)
+	}
+	db := f.postgres
+	if db == nil {
+		return errors.New("postgres is not connected, call ConnectPostgres first")
+	}
+	if err := migrate(ctx, db); err != nil {
+		return err
+	}
+
+	next, found, err := loadCheckpoint(ctx, db)
+	if err != nil {
+		return err
+	}
+	if !found {
+		if config.start != nil {
+			next = *config.start
+		} else {
+			_, eth, err := f.EthClients()
+			if err != nil {
+				return err
+			}
+			if next, err = eth.BlockNumber(ctx); err != nil {
+				return fmt.Errorf("failed to read the head block: %w", err)
+			}
+		}
+	}
+
+	heads := make(chan *types.Header, 16)
+	var sub ethereum.Subscription
+	defer func() {
+		if sub != nil {
+			sub.Unsubscribe()
+		}
+	}()
+	ticker := time.NewTicker(config.poll)
+	defer ticker.Stop()
+
+	for {
+		if sub == nil {
+			if _, eth, err := f.EthClients(); err == nil {
+				// Endpoints without push support are polled only.
+				sub, _ = eth.SubscribeNewHead(ctx, heads)
+			}
+		}
+		var subErr <-chan error
+		if sub != nil {
+			subErr = sub.Err()
+		}
+
+		if err := f.catchUp(ctx, db, config, &next); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			var deep *reorgError
+			if errors.As(err, &deep) {
+				return err
+			}
+			log.Printf("Indexer stopped at block %d, retrying: %v", next, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-heads:
+		case <-ticker.C:
+		case <-subErr:
+			sub = nil
+		}
+	}
+}
+
+// reorgError reports a reorg deeper than the configured depth.
+type reorgError struct {
+	block uint64
+	depth uint64
+}
+
+func (e *reorgError) Error() string {
+	return fmt.Sprintf("reorg at block %d is deeper than %d blocks", e.block, e.depth)
+}
+
+// catchUp indexes blocks from next up to the current head, advancing next.
+func (f *Fx) catchUp(ctx context.Context, db *sql.DB, config *indexConfig, next *uint64) error {
+	_, eth, err := f.EthClients()
+	if err != nil {
+		return err
+	}
+	head, err := eth.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read the head block: %w", err)
+	}
+	chainID, err := eth.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read the chain id: %w", err)
+	}
+	signer := types.LatestSignerForChainID(chainID)
+
+	for *next <= head {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		block, err := eth.BlockByNumber(ctx, new(big.Int).SetUint64(*next))
+		if err != nil {
+			return fmt.Errorf("failed to fetch block %d: %w", *next, err)
+		}
+
+		if *next > 0 {
+			parent, err := storedHash(ctx, db, *next-1)
+			if err != nil {
+				return err
+			}
+			if parent != nil && !bytes.Equal(parent, block.ParentHash().Bytes()) {
+				fork, err := rollback(ctx, db, eth, *next-1, config.reorgDepth)
+				if err != nil {
+					return err
+				}
+				log.Printf("Indexer rolled back blocks after %d for a reorg at %d", fork, *next)
+				*next = fork + 1
+				continue
+			}
+		}
+
+		hash := block.Hash()
+		logs, err := eth.FilterLogs(ctx, ethereum.FilterQuery{BlockHash: &hash, Addresses: config.addresses, Topics: config.topics})
+		if err != nil {
+			return fmt.Errorf("failed to fetch logs of block %d: %w", *next, err)
+		}
+		if err := storeBlock(ctx, db, signer, block, logs); err != nil {
+			return err
+		}
+		*next++
+	}
+	return nil
+}
+
+// migrate applies the pending indexMigrations under an advisory lock, so concurrent indexers migrate once.
+func migrate(ctx context.Context, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('eth_migrations'))`); err != nil {
+		return fmt.Errorf("failed to lock migrations: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS eth_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return fmt.Errorf("failed to create eth_migrations: %w", err)
+	}
+	var applied int
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM eth_migrations`).Scan(&applied); err != nil {
+		return fmt.Errorf("failed to read the schema version: %w", err)
+	}
+	for version := applied + 1; version <= len(indexMigrations); version++ {
+		if _, err := tx.ExecContext(ctx, indexMigrations[version-1]); err != nil {
+			return fmt.Errorf("failed to apply migration %d: %w", version, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO eth_migrations (version) VALUES ($1)`, version); err != nil {
+			return fmt.Errorf("failed to record migration %d: %w", version, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// loadCheckpoint returns the block after the last indexed one.
+func loadCheckpoint(ctx context.Context, db *sql.DB) (uint64, bool, error) {
+	var number int64
+	err := db.QueryRowContext(ctx, `SELECT block_number FROM eth_checkpoint`).Scan(&number)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read the checkpoint: %w", err)
+	}
+	return uint64(number) + 1, true, nil
+}
+
+// storedHash returns the hash of the indexed block number, nil when it is not indexed.
+func storedHash(ctx context.Context, db *sql.DB, number uint64) ([]byte, error) {
+	var hash []byte
+	err := db.QueryRowContext(ctx, `SELECT hash FROM eth_blocks WHERE number = $1`, int64(number)).Scan(&hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read block %d: %w", number, err)
+	}
+	return hash, nil
+}
+
+// rollback walks back from the indexed block number to the last one still on the canonical chain, deletes the blocks
+// after it with their transactions and logs, and moves the checkpoint there.
+func rollback(ctx context.Context, db *sql.DB, eth *ethclient.Client, number uint64, depth uint64) (uint64, error) {
+	fork := number
+	for {
+		if number-fork >= depth {
+			return 0, &reorgError{block: number + 1, depth: depth}
+		}
+		if fork == 0 {
+			break
+		}
+		fork--
+		stored, err := storedHash(ctx, db, fork)
+		if err != nil {
+			return 0, err
+		}
+		header, err := eth.HeaderByNumber(ctx, new(big.Int).SetUint64(fork))
+		if err != nil {
+			return 0, fmt.Errorf("failed to fetch header %d: %w", fork, err)
+		}
+		if stored == nil || bytes.Equal(stored, header.Hash().Bytes()) {
+			break
+		}
+	}
+
+	header, err := eth.HeaderByNumber(ctx, new(big.Int).SetUint64(fork))
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch header %d: %w", fork, err)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin rollback: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM eth_blocks WHERE number > $1`, int64(fork)); err != nil {
+		return 0, fmt.Errorf("failed to delete orphaned blocks: %w", err)
+	}
+	if err := saveCheckpoint(ctx, tx, fork, header.Hash()); err != nil {
+		return 0, err
+	}
+	return fork, tx.Commit()
+}
+
+// storeBlock writes block, its transactions and logs and advances the checkpoint in one transaction.
+func storeBlock(ctx context.Context, db *sql.DB, signer types.Signer, block *types.Block, logs []types.Log) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin block %d: %w", block.NumberU64(), err)
+	}
+	defer tx.Rollback()
+
+	number := int64(block.NumberU64())
+	if _, err := tx.ExecContext(ctx, `INSERT INTO eth_blocks
+		(number, hash, parent_hash, time, miner, gas_used, gas_limit, base_fee, tx_count)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		number, block.Hash().Bytes(), block.ParentHash().Bytes(), time.Unix(int64(block.Time()), 0).UTC(),
+		block.Coinbase().Bytes(), int64(block.GasUsed()), int64(block.GasLimit()), numeric(block.BaseFee()), len(block.Transactions()),
+	); err != nil {
+		return fmt.Errorf("failed to store block %d: %w", number, err)
+	}
+
+	insertTx, err := tx.PrepareContext(ctx, `INSERT INTO eth_transactions
+		(hash, block_number, tx_index, from_address, to_address, value, nonce, gas, gas_price, max_fee, max_priority_fee, input)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare transactions: %w", err)
+	}
+	defer insertTx.Close()
+	for i, transaction := range block.Transactions() {
+		// Transactions without a recoverable sender, such as L2 deposits, are stored without one.
+		var from, to []byte
+		if sender, err := types.Sender(signer, transaction); err == nil {
+			from = sender.Bytes()
+		}
+		if transaction.To() != nil {
+			to = transaction.To().Bytes()
+		}
+		var maxFee, maxPriorityFee any
+		if transaction.Type() != types.LegacyTxType && transaction.Type() != types.AccessListTxType {
+			maxFee, maxPriorityFee = numeric(transaction.GasFeeCap()), numeric(transaction.GasTipCap())
+		}
+		if _, err := insertTx.ExecContext(ctx, transaction.Hash().Bytes(), number, i, from, to,
+			numeric(transaction.Value()), int64(transaction.Nonce()), int64(transaction.Gas()),
+			numeric(effectiveGasPrice(transaction, block.BaseFee())), maxFee, maxPriorityFee, transaction.Data(),
+		); err != nil {
+			return fmt.Errorf("failed to store transaction %s: %w", transaction.Hash(), err)
+		}
+	}
+
+	insertLog, err := tx.PrepareContext(ctx, `INSERT INTO eth_logs
+		(block_number, log_index, tx_hash, address, topic0, topic1, topic2, topic3, data)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare logs: %w", err)
+	}
+	defer insertLog.Close()
+	for _, entry := range logs {
+		topics := make([][]byte, 4)
+		for i, topic := range entry.Topics[:min(len(entry.Topics), 4)] {
+			topics[i] = topic.Bytes()
+		}
+		if _, err := insertLog.ExecContext(ctx, number, int(entry.Index), entry.TxHash.Bytes(), entry.Address.Bytes(),
+			topics[0], topics[1], topics[2], topics[3], entry.Data,
+		); err != nil {
+			return fmt.Errorf("failed to store log %d of block %d: %w", entry.Index, number, err)
+		}
+	}
+
+	if err := saveCheckpoint(ctx, tx, block.NumberU64(), block.Hash()); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit block %d: %w", number, err)
+	}
+	return nil
+}
+
+func saveCheckpoint(ctx context.Context, tx *sql.Tx, number uint64, hash common.Hash) error {
+	if _, err := tx.ExecContext(ctx, `INSERT INTO eth_checkpoint (block_number, block_hash) VALUES ($1, $2)
+		ON CONFLICT (id) DO UPDATE SET block_number = EXCLUDED.block_number, block_hash = EXCLUDED.block_hash, updated_at = now()`,
+		int64(number), hash.Bytes(),
+	); err != nil {
+		return fmt.Errorf("failed to save the checkpoint at block %d: %w", number, err)
+	}
+	return nil
+}
+
+// effectiveGasPrice returns the price per gas transaction paid in a block with baseFee: the base fee plus the tip
+// its caps allow, or the gas price before London.
+func effectiveGasPrice(transaction *types.Transaction, baseFee *big.Int) *big.Int {
+	if baseFee == nil {
+		return transaction.GasPrice()
+	}
+	tip, err := transaction.EffectiveGasTip(baseFee)
+	if err != nil {
+		// Fee-less system transactions, such as L2 deposits, pay no base fee.
+		return transaction.GasPrice()
+	}
+	return tip.Add(tip, baseFee)
+}
+
+// numeric passes a big integer to a NUMERIC column, nil as NULL.
+func numeric(value *big.Int) any {
+	if value == nil {
+		return nil
+	}
+	return value.String()
+}
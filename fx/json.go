@@ -1,45 +1,22 @@
 package fx
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"sync"
+	"reflect"
+	"time"
 )
 
-// GetOption represents a function that modifies a GET request
+// GetOption represents a function that modifies an outgoing request
 type GetOption func(*http.Request)
 
-// Get executes Http GET requests with the given URL and options
+// In executes Http GET requests with the given URL and options
 func (f *Fx) In(endpoint string, options ...GetOption) ([]byte, error) {
-	req, err := f.createRequest(endpoint)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, option := range options {
-		option(req)
-	}
-	return f.executeRequest(req)
-}
-
-// Out writes single response for http requests, using a function to source data and a locker to synchronize access or an HTTP 500 error when the input function fails or JSON encoding fails.
-func (f *Fx) Out(w http.ResponseWriter, input func() (any, error), locker sync.Locker) {
-	locker.Lock()
-	data, err := input()
-	locker.Unlock()
-
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+	return f.Send(http.MethodGet, endpoint, options...)
 }
 
 // Print value as indented JSON to the standard output or logs error when value cannot be marshaled.
@@ -52,33 +29,57 @@ func (f *Fx) Print(value any) {
 	fmt.Println(string(json))
 }
 
-// createRequest creates and configures the base Http GET request
-func (f *Fx) createRequest(endpoint string) (*http.Request, error) {
+// createRequest creates the base Http request and applies the options to it
+func (f *Fx) createRequest(method, endpoint string, options ...GetOption) (*http.Request, error) {
 	parsedURL, err := url.Parse(endpoint)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse URL %s: %w", endpoint, err)
 	}
 
-	req, err := http.NewRequestWithContext(f.Context, "GET", parsedURL.String(), nil)
+	settings := &requestSettings{}
+	ctx := context.WithValue(f.Context, settingsKey{}, settings)
+	req, err := http.NewRequestWithContext(ctx, method, parsedURL.String(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create GET request for URL %s: %w", parsedURL.String(), err)
+		return nil, fmt.Errorf("failed to create %s request for URL %s: %w", method, parsedURL.String(), err)
 	}
 	req.Header.Set("Accept", "application/json")
+
+	for _, option := range options {
+		option(req)
+	}
+	if settings.err != nil {
+		return nil, fmt.Errorf("failed to build %s request for URL %s: %w", method, parsedURL.String(), settings.err)
+	}
 	return req, nil
 }
 
-// executeRequest executes the Http request and returns the response body
+// executeRequest executes the Http request and returns the response body, projected when the request asks for it
 func (f *Fx) executeRequest(req *http.Request) ([]byte, error) {
-	resp, err := f.Http.Do(req)
+	body, err := f.fetchBody(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute GET request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	if paths := settingsOf(req).projection; len(paths) > 0 {
+		return project(body, paths)
+	}
+	return body, nil
+}
 
-	// Check for successful status codes (2xx range)
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+// fetchBody returns the response body from the cache or the upstream
+func (f *Fx) fetchBody(req *http.Request) ([]byte, error) {
+	if store := f.cacheFor(req); store != nil {
+		return f.executeCached(req, store)
+	}
+	resp, err := f.do(req)
+	if err != nil {
+		return nil, err
 	}
+	return readBody(resp)
+}
+
+// readBody reads and closes the response body
+func readBody(resp *http.Response) ([]byte, error) {
+	defer resp.Body.Close()
 
 	// Handle empty response body
 	if resp.ContentLength == 0 {
@@ -92,6 +93,90 @@ func (f *Fx) executeRequest(req *http.Request) ([]byte, error) {
 	return body, nil
 }
 
+// do sends the request, applying rate limits, timeouts and retries, and returns the first 2xx response. The caller closes its body.
+func (f *Fx) do(req *http.Request) (*http.Response, error) {
+	settings := settingsOf(req)
+	limiter := f.limits.forRequest(req.URL.Host, settings)
+
+	for attempt := 1; ; attempt++ {
+		if limiter != nil {
+			if err := limiter.wait(req.Context()); err != nil {
+				return nil, fmt.Errorf("rate limit wait for %s: %w", req.URL.Host, err)
+			}
+		}
+
+		resp, err := f.attempt(req, attempt, settings.timeout)
+		if err == nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			if settings.bodyLimit > 0 {
+				resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: settings.bodyLimit}
+			}
+			return resp, nil
+		}
+
+		var failure error
+		if err != nil {
+			failure = fmt.Errorf("failed to execute %s request: %w", req.Method, err)
+		} else {
+			// Check for successful status codes (2xx range)
+			failure = newHTTPError(req, resp)
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		delay, retry := settings.retry.next(req, attempt, resp, err)
+		if !retry {
+			return nil, f.errs.decode(req.URL.Host, failure)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%w (retry abandoned: %w)", failure, req.Context().Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends one try of the request, rewinding its body after the first and bounding it by timeout when set.
+func (f *Fx) attempt(req *http.Request, attempt int, timeout time.Duration) (*http.Response, error) {
+	try := req
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		var ctx context.Context
+		ctx, cancel = context.WithTimeout(req.Context(), timeout)
+		try = req.WithContext(ctx)
+	}
+	if attempt > 1 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		try.Body = body
+	}
+
+	resp, err := f.Http.Do(try)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody releases the attempt's timeout context once the body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
 // Header option builders
 func WithHeader(key, value string) GetOption {
 	return func(req *http.Request) {
@@ -154,36 +239,51 @@ func WithQueryMap(params map[string]string) GetOption {
 	}
 }
 
-// Simplify processes any input, flattens it, and removes empty fields.
-func (f *Fx) Simplify(input any) any {
+// Simplify processes any input, including typed structs, flattens it, and removes empty fields.
+func (f *Fx) Simplify(input any, options ...SimplifyOption) any {
+	config := newFlatConfig(options)
 	result := make(map[string]any)
 
 	// Use a stack to avoid recursion
 	type stackItem struct {
 		value  any
 		prefix string
+		depth  int
 	}
 
-	stack := []stackItem{{value: input, prefix: ""}}
+	stack := []stackItem{{value: normalize(reflect.ValueOf(input)), prefix: ""}}
 
 	for len(stack) > 0 {
 		// Pop from stack
 		item := stack[len(stack)-1]
 		stack = stack[:len(stack)-1]
 
+		if config.maxDepth > 0 && item.depth == config.maxDepth {
+			if config.keepEmpty || !isEmpty(item.value) {
+				result[item.prefix] = item.value
+			}
+			continue
+		}
+
 		switch v := item.value.(type) {
 		case map[string]any:
+			if len(v) == 0 && config.keepEmpty && item.prefix != "" {
+				result[item.prefix] = v
+			}
 			for key, value := range v {
-				newKey := buildKey(item.prefix, key)
-				stack = append(stack, stackItem{value: value, prefix: newKey})
+				newKey := config.buildKey(item.prefix, key)
+				stack = append(stack, stackItem{value: value, prefix: newKey, depth: item.depth + 1})
 			}
 		case []any:
+			if len(v) == 0 && config.keepEmpty && item.prefix != "" {
+				result[item.prefix] = v
+			}
 			for i, value := range v {
-				arrayKey := buildArrayKey(item.prefix, i)
-				stack = append(stack, stackItem{value: value, prefix: arrayKey})
+				arrayKey := config.buildArrayKey(item.prefix, i)
+				stack = append(stack, stackItem{value: value, prefix: arrayKey, depth: item.depth + 1})
 			}
 		default:
-			if !isEmpty(v) {
+			if config.keepEmpty || !isEmpty(v) {
 				result[item.prefix] = v
 			}
 		}
@@ -192,23 +292,11 @@ func (f *Fx) Simplify(input any) any {
 	if len(result) == 0 {
 		return nil
 	}
-
-	return any(result)
-}
-
-func buildKey(prefix, key string) string {
-	if prefix == "" {
-		return key
+	if config.sorted {
+		return config.sortFields(result)
 	}
-	return prefix + "." + key
-}
 
-func buildArrayKey(prefix string, index int) string {
-	arrayKey := fmt.Sprintf("[%d]", index)
-	if prefix == "" {
-		return arrayKey
-	}
-	return prefix + arrayKey
+	return any(result)
 }
 
 // isEmpty checks if a value is considered empty
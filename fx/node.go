@@ -0,0 +1,256 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// defaultEndpoint is the node used when neither Node nor ETH_ENDPOINTS name one.
+const defaultEndpoint = "ipc:///.ethereum/geth.ipc"
+
+// NodeOption configures Node.
+type NodeOption func(*nodeConfig)
+
+type nodeConfig struct {
+	retry          RetryPolicy
+	healthInterval time.Duration
+	healthTimeout  time.Duration
+}
+
+// WithDialRetry retries connecting when every endpoint fails. Defaults to 5 rounds from 1s up to 30s apart,
+// an unset BaseDelay or MaxDelay keeps its default.
+func WithDialRetry(policy RetryPolicy) NodeOption {
+	return func(c *nodeConfig) {
+		c.retry = policy.withDefaults(time.Second)
+	}
+}
+
+// WithHealthCheck probes the active endpoint every interval and fails over when a probe does not answer within timeout,
+// which also bounds connecting to an endpoint. Defaults to 15s and 5s, a zero interval disables failover and a zero
+// timeout keeps the default.
+func WithHealthCheck(interval, timeout time.Duration) NodeOption {
+	return func(c *nodeConfig) {
+		c.healthInterval = interval
+		if timeout > 0 {
+			c.healthTimeout = timeout
+		}
+	}
+}
+
+// node tracks the active Ethereum endpoint and fails over between the configured ones.
+type node struct {
+	sync.RWMutex
+	endpoints []string
+	active    int
+	chainID   string
+	rpc       *rpc.Client
+	eth       *ethclient.Client
+	stop      context.CancelFunc // ends the monitor
+	closed    bool
+}
+
+// close stops monitoring n and closes its client.
+func (n *node) close() {
+	n.stop()
+	n.Lock()
+	defer n.Unlock()
+	n.closed = true
+	if n.rpc != nil {
+		n.rpc.Close()
+	}
+}
+
+// Node connects to the first healthy Ethereum endpoint, ipc:///path (or a bare path), http(s):// or ws(s)://.
+// Without endpoints it reads a comma separated list from ETH_ENDPOINTS, falling back to the local geth.ipc.
+// Connecting retries with backoff, and a health check fails over to another endpoint on the same chain.
+// EthClients returns the clients of the endpoint active at the time of the call. Calling Node again replaces the
+// connection, closing the previous clients once the new ones connect.
+func (f *Fx) Node(endpoints []string, options ...NodeOption) error {
+	if len(endpoints) == 0 {
+		if env := os.Getenv("ETH_ENDPOINTS"); env != "" {
+			for endpoint := range strings.SplitSeq(env, ",") {
+				if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+					endpoints = append(endpoints, endpoint)
+				}
+			}
+		}
+	}
+	if len(endpoints) == 0 {
+		endpoints = []string{defaultEndpoint}
+	}
+
+	config := &nodeConfig{
+		retry:          RetryPolicy{Attempts: 5, BaseDelay: time.Second, MaxDelay: 30 * time.Second},
+		healthInterval: 15 * time.Second,
+		healthTimeout:  5 * time.Second,
+	}
+	for _, option := range options {
+		option(config)
+	}
+
+	ctx, stop := context.WithCancel(f.Context)
+	n := &node{endpoints: endpoints, stop: stop}
+	var failures []error
+	for attempt := 1; ; attempt++ {
+		err := f.failover(n, 0, config.healthTimeout)
+		if err == nil {
+			break
+		}
+		failures = append(failures, err)
+		if attempt >= config.retry.Attempts {
+			stop()
+			return fmt.Errorf("failed to connect to the Ethereum client after %d attempts: %w", attempt, errors.Join(failures...))
+		}
+		select {
+		case <-f.Context.Done():
+			stop()
+			return f.Context.Err()
+		case <-time.After(config.retry.backoff(attempt)):
+		}
+	}
+
+	if previous := f.node.Swap(n); previous != nil {
+		previous.close()
+	}
+	if config.healthInterval > 0 {
+		go f.monitor(ctx, n, config)
+	}
+	return nil
+}
+
+// EthClients returns the clients of the active endpoint, or an error before Node has connected.
+func (f *Fx) EthClients() (*rpc.Client, *ethclient.Client, error) {
+	n := f.node.Load()
+	if n == nil {
+		return nil, nil, errors.New("ethereum client is not connected, call Node first")
+	}
+	n.RLock()
+	defer n.RUnlock()
+	return n.rpc, n.eth, nil
+}
+
+// chainID returns the chain id reported by the active endpoint, empty before Node has connected.
+func (f *Fx) chainID() string {
+	n := f.node.Load()
+	if n == nil {
+		return ""
+	}
+	n.RLock()
+	defer n.RUnlock()
+	return n.chainID
+}
+
+// monitor probes the active endpoint until ctx ends, failing over when it stops answering.
+func (f *Fx) monitor(ctx context.Context, n *node, config *nodeConfig) {
+	ticker := time.NewTicker(config.healthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n.RLock()
+		client, active := n.rpc, n.active
+		n.RUnlock()
+		if _, err := probe(ctx, client, config.healthTimeout); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Ethereum endpoint %s is unhealthy: %v", n.endpoints[active], err)
+			if err := f.failover(n, active+1, config.healthTimeout); err != nil {
+				log.Printf("Ethereum failover failed, keeping %s: %v", n.endpoints[active], err)
+			}
+		}
+	}
+}
+
+// failover connects to the first healthy endpoint starting at index start, wrapping around, and makes it active.
+func (f *Fx) failover(n *node, start int, timeout time.Duration) error {
+	var failures []error
+	for i := range n.endpoints {
+		index := (start + i) % len(n.endpoints)
+		endpoint := n.endpoints[index]
+		client, chainID, err := f.dialNode(endpoint, timeout)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("%s: %w", endpoint, err))
+			continue
+		}
+
+		n.Lock()
+		if n.closed {
+			// Node was called again meanwhile.
+			n.Unlock()
+			client.Close()
+			return errors.New("connection replaced")
+		}
+		if n.chainID != "" && chainID != n.chainID {
+			n.Unlock()
+			client.Close()
+			failures = append(failures, fmt.Errorf("%s: chain id %s differs from %s", endpoint, chainID, n.chainID))
+			continue
+		}
+		previous := n.rpc
+		n.rpc, n.eth, n.active, n.chainID = client, ethclient.NewClient(client), index, chainID
+		n.Unlock()
+
+		if previous != nil {
+			log.Printf("Ethereum client failed over to %s", endpoint)
+			previous.Close()
+		}
+		return nil
+	}
+	return errors.Join(failures...)
+}
+
+// dialNode connects to endpoint and verifies it answers eth_chainId.
+func (f *Fx) dialNode(endpoint string, timeout time.Duration) (*rpc.Client, string, error) {
+	ctx, cancel := context.WithTimeout(f.Context, timeout)
+	defer cancel()
+
+	var client *rpc.Client
+	var err error
+	switch {
+	case strings.HasPrefix(endpoint, "ipc://"):
+		client, err = rpc.DialIPC(ctx, strings.TrimPrefix(endpoint, "ipc://"))
+	case strings.HasPrefix(endpoint, "http://"), strings.HasPrefix(endpoint, "https://"):
+		client, err = rpc.DialOptions(ctx, endpoint, rpc.WithHTTPClient(f.Http))
+	case strings.HasPrefix(endpoint, "ws://"), strings.HasPrefix(endpoint, "wss://"):
+		client, err = rpc.DialOptions(ctx, endpoint)
+	case !strings.Contains(endpoint, "://"):
+		client, err = rpc.DialIPC(ctx, endpoint)
+	default:
+		return nil, "", fmt.Errorf("unsupported endpoint scheme")
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	chainID, err := probe(f.Context, client, timeout)
+	if err != nil {
+		client.Close()
+		return nil, "", err
+	}
+	return client, chainID, nil
+}
+
+// probe asks the node for its chain id within timeout.
+func probe(ctx context.Context, client *rpc.Client, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var chainID string
+	if err := client.CallContext(ctx, &chainID, "eth_chainId"); err != nil {
+		return "", fmt.Errorf("health check failed: %w", err)
+	}
+	return chainID, nil
+}
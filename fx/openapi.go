@@ -0,0 +1,353 @@
+package fx
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/zachklingbeil/factory/zero"
+)
+
+// OpenAPIDoc is an OpenAPI 3.1 document describing the routes on the router.
+type OpenAPIDoc struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       OpenAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+	Components OpenAPIComponents                       `json:"components"`
+}
+
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type OpenAPIComponents struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId,omitempty"`
+	Parameters  []OpenAPIParameter          `json:"parameters,omitempty"`
+	RequestBody *OpenAPIBody                `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+}
+
+type OpenAPIParameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type OpenAPIBody struct {
+	Required bool                    `json:"required,omitempty"`
+	Content  map[string]OpenAPIMedia `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                  `json:"description"`
+	Content     map[string]OpenAPIMedia `json:"content,omitempty"`
+}
+
+type OpenAPIMedia struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is a JSON Schema as used by OpenAPI 3.1.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Minimum              *int               `json:"minimum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// operation records the request and response types of a route registered with Handle.
+type operation struct {
+	request  reflect.Type
+	response reflect.Type
+}
+
+// OpenAPI describes every route on the router. Routes registered with Handle get parameter, body and response schemas
+// from their types, other routes are listed with their path parameters. Route names become operation ids.
+func (f *Fx) OpenAPI(title, version string) *OpenAPIDoc {
+	doc := &OpenAPIDoc{
+		OpenAPI:    "3.1.0",
+		Info:       OpenAPIInfo{Title: title, Version: version},
+		Paths:      make(map[string]map[string]*OpenAPIOperation),
+		Components: OpenAPIComponents{Schemas: make(map[string]*Schema)},
+	}
+	schemas := &schemaBuilder{components: doc.Components.Schemas}
+	problem := schemas.of(reflect.TypeFor[Problem]())
+
+	f.Zero.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			methods = []string{http.MethodGet}
+		}
+		path, vars := openAPIPath(template)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*OpenAPIOperation)
+		}
+
+		op := f.operations[route]
+		for _, method := range methods {
+			operation := &OpenAPIOperation{
+				OperationID: route.GetName(),
+				Responses: map[string]*OpenAPIResponse{
+					"200": {Description: "OK"},
+				},
+			}
+			if op != nil {
+				schemas.describe(operation, method, op, problem)
+			}
+			for _, name := range vars {
+				if !slices.ContainsFunc(operation.Parameters, func(p OpenAPIParameter) bool { return p.In == "path" && p.Name == name }) {
+					operation.Parameters = append(operation.Parameters, OpenAPIParameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+				}
+			}
+			doc.Paths[path][strings.ToLower(method)] = operation
+		}
+		return nil
+	})
+	return doc
+}
+
+// ServeOpenAPI serves the OpenAPI document at path, /openapi.json when empty, rebuilt on each request.
+func (f *Fx) ServeOpenAPI(path, title, version string) *mux.Route {
+	if path == "" {
+		path = "/openapi.json"
+	}
+	return f.Zero.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		f.Respond(w, r, func() (any, error) {
+			return f.OpenAPI(title, version), nil
+		}, WithETag())
+	}).Methods(http.MethodGet)
+}
+
+// APIReference renders the OpenAPI document as a frame listing each operation with its parameters, body and response.
+// The frame is a snapshot of the routes registered so far.
+func (f *Fx) APIReference(title, version string) *zero.One {
+	doc := f.OpenAPI(title, version)
+	e := f.Zero.Element
+
+	parts := []zero.One{*e.H1(title), *e.Paragraph("version " + version)}
+	for _, path := range slices.Sorted(maps.Keys(doc.Paths)) {
+		for _, method := range slices.Sorted(maps.Keys(doc.Paths[path])) {
+			op := doc.Paths[path][method]
+			section := []zero.One{*e.H3(strings.ToUpper(method) + " " + path)}
+			if op.OperationID != "" {
+				section = append(section, *e.Paragraph(op.OperationID))
+			}
+			if len(op.Parameters) > 0 {
+				rows := [][]string{{"name", "in", "type", "required"}}
+				for _, p := range op.Parameters {
+					rows = append(rows, []string{p.Name, p.In, schemaLabel(p.Schema), fmt.Sprint(p.Required)})
+				}
+				section = append(section, *e.H4("parameters"), *e.Table(4, uint64(len(rows)), rows))
+			}
+			if op.RequestBody != nil {
+				section = append(section, *e.H4("body"), *e.Code(schemaJSON(op.RequestBody.Content["application/json"].Schema, doc)))
+			}
+			if ok := op.Responses["200"]; ok != nil && ok.Content != nil {
+				section = append(section, *e.H4("response"), *e.Code(schemaJSON(ok.Content["application/json"].Schema, doc)))
+			}
+			parts = append(parts, *f.Lego("operation", section...))
+		}
+	}
+	return f.Lego("api", parts...)
+}
+
+// schemaLabel names the type of a parameter schema.
+func schemaLabel(s *Schema) string {
+	if s.Type == "array" && s.Items != nil {
+		return s.Items.Type + "[]"
+	}
+	return s.Type
+}
+
+// schemaJSON renders s, resolving a top-level reference into the components.
+func schemaJSON(s *Schema, doc *OpenAPIDoc) string {
+	if s != nil && s.Ref != "" {
+		if resolved, ok := doc.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]; ok {
+			s = resolved
+		}
+	}
+	data, _ := json.MarshalIndent(s, "", "  ")
+	return string(data)
+}
+
+var routeVar = regexp.MustCompile(`\{([^{}:]+)(:[^{}]*(\{[^{}]*\}[^{}]*)*)?\}`)
+
+// openAPIPath turns a mux template such as /items/{id:[0-9]+} into /items/{id} and returns the variable names.
+func openAPIPath(template string) (string, []string) {
+	var vars []string
+	path := routeVar.ReplaceAllStringFunc(template, func(match string) string {
+		name := routeVar.FindStringSubmatch(match)[1]
+		vars = append(vars, name)
+		return "{" + name + "}"
+	})
+	return path, vars
+}
+
+// schemaBuilder builds schemas, placing named structs in the components.
+type schemaBuilder struct {
+	components map[string]*Schema
+}
+
+// describe fills the parameters, body and responses of operation from the types recorded by Handle.
+func (b *schemaBuilder) describe(operation *OpenAPIOperation, method string, op *operation, problem *Schema) {
+	request := op.request
+	for request.Kind() == reflect.Pointer {
+		request = request.Elem()
+	}
+
+	hasBody := false
+	if request.Kind() == reflect.Struct {
+		body := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		for _, field := range reflect.VisibleFields(request) {
+			if !field.IsExported() || field.Anonymous {
+				continue
+			}
+			required := field.Tag.Get("validate") == "required"
+			if name, ok := field.Tag.Lookup("path"); ok {
+				operation.Parameters = append(operation.Parameters, OpenAPIParameter{Name: name, In: "path", Required: true, Schema: b.of(field.Type)})
+				continue
+			}
+			if name, ok := field.Tag.Lookup("query"); ok {
+				operation.Parameters = append(operation.Parameters, OpenAPIParameter{Name: name, In: "query", Required: required, Schema: b.of(field.Type)})
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			body.Properties[name] = b.of(field.Type)
+			if required {
+				body.Required = append(body.Required, name)
+			}
+		}
+		hasBody = len(body.Properties) > 0
+		if hasBody && method != http.MethodGet && method != http.MethodHead {
+			operation.RequestBody = &OpenAPIBody{
+				Required: len(body.Required) > 0,
+				Content:  map[string]OpenAPIMedia{"application/json": {Schema: body}},
+			}
+		}
+	}
+
+	operation.Responses["200"] = &OpenAPIResponse{
+		Description: "OK",
+		Content:     map[string]OpenAPIMedia{"application/json": {Schema: b.of(op.response)}},
+	}
+	operation.Responses["default"] = &OpenAPIResponse{
+		Description: "Error",
+		Content:     map[string]OpenAPIMedia{"application/problem+json": {Schema: problem}},
+	}
+}
+
+var (
+	timeType  = reflect.TypeFor[time.Time]()
+	bytesType = reflect.TypeFor[[]byte]()
+)
+
+// of returns the schema for t, referencing named structs through the components.
+func (b *schemaBuilder) of(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == bytesType:
+		return &Schema{Type: "string", Format: "byte"}
+	case t.Implements(textMarshaler) || reflect.PointerTo(t).Implements(textMarshaler):
+		return &Schema{Type: "string"}
+	case t.Implements(jsonMarshaler) || reflect.PointerTo(t).Implements(jsonMarshaler):
+		return &Schema{}
+	}
+
+	zero := 0
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: &zero}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: b.of(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.of(t.Elem())}
+	case reflect.Struct:
+		return b.object(t)
+	}
+	return &Schema{}
+}
+
+var componentName = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// object returns a reference to the component schema of a named struct, building it once, or an inline schema for anonymous ones.
+func (b *schemaBuilder) object(t reflect.Type) *Schema {
+	name := componentName.ReplaceAllString(t.String(), "_")
+	if t.Name() != "" {
+		ref := &Schema{Ref: "#/components/schemas/" + name}
+		if _, exists := b.components[name]; exists {
+			return ref
+		}
+		b.components[name] = &Schema{} // placeholder that stops recursion
+		b.components[name] = b.properties(t)
+		return ref
+	}
+	return b.properties(t)
+}
+
+func (b *schemaBuilder) properties(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || (field.Anonymous && field.Tag.Get("json") == "") {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if _, exists := schema.Properties[name]; exists {
+			continue
+		}
+		schema.Properties[name] = b.of(field.Type)
+		if field.Tag.Get("validate") == "required" {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
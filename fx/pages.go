@@ -0,0 +1,365 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrMaxPages is returned by Pages when the last page was not reached within the page limit.
+var ErrMaxPages = errors.New("page limit reached before the last page")
+
+// Page is one response fetched by Pages.
+type Page struct {
+	Number int // zero-based
+	URL    *url.URL
+	Header http.Header
+	Body   []byte
+}
+
+// Paginator finds the page that follows the current one.
+type Paginator interface {
+	// Next returns the URL of the page after page, or nil when page is the last.
+	Next(page *Page) (*url.URL, error)
+}
+
+// IndexedPaginator addresses pages by number, which lets Pages prefetch them concurrently.
+type IndexedPaginator interface {
+	Paginator
+	// PageURL returns the URL of page n derived from the URL of any other page.
+	PageURL(base *url.URL, n int) *url.URL
+}
+
+// WithMaxPages stops Pages with ErrMaxPages after max pages. Defaults to 100.
+func WithMaxPages(max int) GetOption {
+	return func(req *http.Request) {
+		settingsOf(req).maxPages = max
+	}
+}
+
+// WithPrefetch fetches up to n pages concurrently when the paginator is an IndexedPaginator.
+func WithPrefetch(n int) GetOption {
+	return func(req *http.Request) {
+		settingsOf(req).prefetch = n
+	}
+}
+
+// Pages iterates the pages of a paginated GET endpoint in order. Iteration stops after the first error.
+// Pages bypasses the response cache, since pagination depends on the live response headers.
+func (f *Fx) Pages(endpoint string, paginator Paginator, options ...GetOption) iter.Seq2[*Page, error] {
+	return func(yield func(*Page, error) bool) {
+		first, err := f.createRequest(http.MethodGet, endpoint, options...)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		settings := settingsOf(first)
+		maxPages := settings.maxPages
+		if maxPages <= 0 {
+			maxPages = 100
+		}
+
+		req := first
+		if indexed, ok := paginator.(IndexedPaginator); ok {
+			if settings.prefetch > 1 {
+				f.prefetchPages(first, indexed, maxPages, settings.prefetch, yield)
+				return
+			}
+			req = pageRequest(first, indexed.PageURL(first.URL, 0))
+		}
+		for n := 0; ; n++ {
+			if n == maxPages {
+				yield(nil, fmt.Errorf("%s: %w (%d pages)", endpoint, ErrMaxPages, maxPages))
+				return
+			}
+			page, err := f.fetchPage(req, n)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(page, nil) {
+				return
+			}
+			next, err := paginator.Next(page)
+			if err != nil {
+				yield(nil, fmt.Errorf("failed to find page %d of %s: %w", n+1, endpoint, err))
+				return
+			}
+			if next == nil {
+				return
+			}
+			req = pageRequest(first, next)
+		}
+	}
+}
+
+// prefetchPages keeps up to window page requests in flight and yields the pages in order.
+func (f *Fx) prefetchPages(first *http.Request, paginator IndexedPaginator, maxPages, window int, yield func(*Page, error) bool) {
+	ctx, cancel := context.WithCancel(first.Context())
+	defer cancel()
+	first = first.WithContext(ctx)
+
+	type result struct {
+		page *Page
+		err  error
+	}
+	var pending []chan result
+	launched := 0
+	launch := func() {
+		n := launched
+		launched++
+		ch := make(chan result, 1)
+		pending = append(pending, ch)
+		go func() {
+			page, err := f.fetchPage(pageRequest(first, paginator.PageURL(first.URL, n)), n)
+			ch <- result{page: page, err: err}
+		}()
+	}
+
+	for launched < window && launched < maxPages {
+		launch()
+	}
+	for n := 0; ; n++ {
+		if n == maxPages {
+			yield(nil, fmt.Errorf("%s: %w (%d pages)", first.URL, ErrMaxPages, maxPages))
+			return
+		}
+		res := <-pending[0]
+		pending = pending[1:]
+		if res.err != nil {
+			yield(nil, res.err)
+			return
+		}
+		if !yield(res.page, nil) {
+			return
+		}
+		next, err := paginator.Next(res.page)
+		if err != nil {
+			yield(nil, fmt.Errorf("failed to find page %d of %s: %w", n+1, first.URL, err))
+			return
+		}
+		if next == nil {
+			return
+		}
+		if launched < maxPages {
+			launch()
+		}
+	}
+}
+
+// pageRequest copies the headers and settings of first onto a request for u. Like a redirect in net/http, a page
+// on another host does not receive the credential headers.
+func pageRequest(first *http.Request, u *url.URL) *http.Request {
+	req := first.Clone(first.Context())
+	req.URL = u
+	req.Host = u.Host
+	if !strings.EqualFold(u.Host, first.URL.Host) {
+		for _, name := range redacted {
+			req.Header.Del(name)
+		}
+	}
+	return req
+}
+
+func (f *Fx) fetchPage(req *http.Request, n int) (*Page, error) {
+	resp, err := f.do(req)
+	if err != nil {
+		return nil, err
+	}
+	header := resp.Header
+	body, err := readBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	return &Page{Number: n, URL: req.URL, Header: header, Body: body}, nil
+}
+
+// LinkHeader follows the rel="next" entry of the Link response header.
+func LinkHeader() Paginator {
+	return linkHeader{}
+}
+
+type linkHeader struct{}
+
+func (linkHeader) Next(page *Page) (*url.URL, error) {
+	for _, header := range page.Header.Values("Link") {
+		for link := range strings.SplitSeq(header, ",") {
+			target, params, found := strings.Cut(link, ";")
+			if !found || !hasRel(params, "next") {
+				continue
+			}
+			next, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+			if err != nil {
+				return nil, err
+			}
+			return page.URL.ResolveReference(next), nil
+		}
+	}
+	return nil, nil
+}
+
+func hasRel(params, rel string) bool {
+	for param := range strings.SplitSeq(params, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if !strings.EqualFold(key, "rel") {
+			continue
+		}
+		for name := range strings.FieldsSeq(strings.Trim(value, `"`)) {
+			if strings.EqualFold(name, rel) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Cursor reads the next cursor from path in the JSON body, such as "meta.next_cursor", and sends it as the param query parameter.
+// An empty or missing cursor ends the iteration.
+func Cursor(path, param string) Paginator {
+	return cursor{path: path, param: param}
+}
+
+type cursor struct {
+	path  string
+	param string
+}
+
+func (c cursor) Next(page *Page) (*url.URL, error) {
+	doc, err := decodePage(page)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := lookup(doc, c.path)
+	if !ok || isEmpty(value) {
+		return nil, nil
+	}
+	var token string
+	switch v := value.(type) {
+	case string:
+		token = v
+	case json.Number:
+		token = v.String()
+	default:
+		return nil, fmt.Errorf("cursor at %s is %T, not a string or number", c.path, value)
+	}
+	return withParams(page.URL, map[string]string{c.param: token}), nil
+}
+
+// Offset pages with offset and limit query parameters, counting the items at itemsPath ("" for a top-level array).
+// A page with fewer than limit items is the last.
+func Offset(offsetParam, limitParam string, limit int, itemsPath string) IndexedPaginator {
+	return offset{offsetParam: offsetParam, limitParam: limitParam, limit: limit, itemsPath: itemsPath}
+}
+
+type offset struct {
+	offsetParam string
+	limitParam  string
+	limit       int
+	itemsPath   string
+}
+
+func (o offset) PageURL(base *url.URL, n int) *url.URL {
+	return withParams(base, map[string]string{
+		o.offsetParam: strconv.Itoa(n * o.limit),
+		o.limitParam:  strconv.Itoa(o.limit),
+	})
+}
+
+func (o offset) Next(page *Page) (*url.URL, error) {
+	count, err := countItems(page, o.itemsPath)
+	if err != nil || count < o.limit || count == 0 {
+		return nil, err
+	}
+	return o.PageURL(page.URL, page.Number+1), nil
+}
+
+// PageNumber pages with a page number query parameter starting at first, counting the items at itemsPath ("" for a top-level array).
+// An empty page ends the iteration.
+func PageNumber(param string, first int, itemsPath string) IndexedPaginator {
+	return pageNumber{param: param, first: first, itemsPath: itemsPath}
+}
+
+type pageNumber struct {
+	param     string
+	first     int
+	itemsPath string
+}
+
+func (p pageNumber) PageURL(base *url.URL, n int) *url.URL {
+	return withParams(base, map[string]string{p.param: strconv.Itoa(p.first + n)})
+}
+
+func (p pageNumber) Next(page *Page) (*url.URL, error) {
+	count, err := countItems(page, p.itemsPath)
+	if err != nil || count == 0 {
+		return nil, err
+	}
+	return p.PageURL(page.URL, page.Number+1), nil
+}
+
+// decodePage decodes the body of page, keeping numbers as json.Number so numeric cursors pass through exactly.
+func decodePage(page *Page) (any, error) {
+	doc, err := decodeJSON(page.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode page %d: %w", page.Number, err)
+	}
+	return doc, nil
+}
+
+func countItems(page *Page, path string) (int, error) {
+	doc, err := decodePage(page)
+	if err != nil {
+		return 0, err
+	}
+	value, ok := lookup(doc, path)
+	if !ok {
+		return 0, nil
+	}
+	items, ok := value.([]any)
+	if !ok {
+		return 0, fmt.Errorf("items at %q is %T, not an array", path, value)
+	}
+	return len(items), nil
+}
+
+func withParams(base *url.URL, params map[string]string) *url.URL {
+	u := *base
+	query := u.Query()
+	for key, value := range params {
+		query.Set(key, value)
+	}
+	u.RawQuery = query.Encode()
+	return &u
+}
+
+// lookup follows a key in Simplify notation, such as "data.items[0].id", through decoded JSON.
+func lookup(doc any, key string) (any, bool) {
+	current := doc
+	for _, segment := range splitKey(key) {
+		switch v := current.(type) {
+		case map[string]any:
+			if segment.isIndex {
+				return nil, false
+			}
+			next, exists := v[segment.name]
+			if !exists {
+				return nil, false
+			}
+			current = next
+		case []any:
+			if !segment.isIndex || segment.index >= len(v) {
+				return nil, false
+			}
+			current = v[segment.index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
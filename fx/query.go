@@ -0,0 +1,775 @@
+package fx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Path is a compiled JSONPath-like query such as $.items[*].id or $..book[?(@.price < 10)].title.
+//
+// Supported: $ (root), .name and ['name'] children, .* and [*] wildcards, ..name recursive descent,
+// [0], [-1] and [0,2] indexes, [1:3] and [::2] slices, ['a','b'] unions and [?(...)] filters.
+// Filters compare @ (current) or $ paths with ==, !=, <, <=, >, >= against literals or paths,
+// test existence with @.name, and combine with &&, || , ! and parentheses.
+type Path struct {
+	expr  string
+	steps []step
+}
+
+// CompilePath parses expr into a Path.
+func CompilePath(expr string) (*Path, error) {
+	p := &pathParser{src: strings.TrimSpace(expr)}
+	if !p.consume("$") {
+		return nil, fmt.Errorf("path %q: must start with $", expr)
+	}
+	steps, err := p.steps()
+	if err != nil {
+		return nil, fmt.Errorf("path %q: %w", expr, err)
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("path %q: unexpected %q at %d", expr, p.src[p.pos:], p.pos)
+	}
+	return &Path{expr: expr, steps: steps}, nil
+}
+
+func (p *Path) String() string {
+	return p.expr
+}
+
+// Eval returns every value in doc matched by the path. doc is decoded JSON or any Go value, which is read through its json tags.
+func (p *Path) Eval(doc any) []any {
+	root := normalize(reflect.ValueOf(doc))
+	return evalSteps(p.steps, root, root)
+}
+
+// EvalBytes decodes data as JSON and evaluates the path against it. Numbers are matched as json.Number, so large
+// integers keep every digit.
+func (p *Path) EvalBytes(data []byte) ([]any, error) {
+	doc, err := decodeJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode JSON for %s: %w", p.expr, err)
+	}
+	return p.Eval(doc), nil
+}
+
+// decodeJSON decodes data like json.Unmarshal into any, but keeps numbers as json.Number rather than float64.
+func decodeJSON(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc any
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("invalid data after top-level value")
+	}
+	return doc, nil
+}
+
+// Query evaluates expr against doc, raw JSON ([]byte or json.RawMessage) or a decoded value, and converts each match to T.
+func Query[T any](doc any, expr string) ([]T, error) {
+	path, err := CompilePath(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []any
+	switch raw := doc.(type) {
+	case []byte:
+		matches, err = path.EvalBytes(raw)
+	case json.RawMessage:
+		matches, err = path.EvalBytes(raw)
+	default:
+		matches = path.Eval(doc)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]T, 0, len(matches))
+	for _, match := range matches {
+		if typed, ok := match.(T); ok {
+			results = append(results, typed)
+			continue
+		}
+		data, err := json.Marshal(match)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode match of %s: %w", expr, err)
+		}
+		var typed T
+		if err := json.Unmarshal(data, &typed); err != nil {
+			return nil, fmt.Errorf("failed to convert match of %s: %w", expr, err)
+		}
+		results = append(results, typed)
+	}
+	return results, nil
+}
+
+// WithProjection replaces the response body with the JSON array of values matched by expr.
+// With several expressions the body is an object of arrays keyed by expression, in the order given.
+func WithProjection(exprs ...string) GetOption {
+	return func(req *http.Request) {
+		settings := settingsOf(req)
+		for _, expr := range exprs {
+			path, err := CompilePath(expr)
+			if err != nil {
+				settings.err = err
+				return
+			}
+			settings.projection = append(settings.projection, path)
+		}
+	}
+}
+
+// project evaluates paths against body and encodes the matches.
+func project(body []byte, paths []*Path) ([]byte, error) {
+	doc, err := decodeJSON(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response for projection: %w", err)
+	}
+	if len(paths) == 1 {
+		return json.Marshal(orEmpty(paths[0].Eval(doc)))
+	}
+	fields := make(Fields, 0, len(paths))
+	for _, path := range paths {
+		fields = append(fields, Field{Key: path.expr, Value: orEmpty(path.Eval(doc))})
+	}
+	return json.Marshal(fields)
+}
+
+// orEmpty keeps an empty match list encoding as [] rather than null.
+func orEmpty(matches []any) []any {
+	if matches == nil {
+		return []any{}
+	}
+	return matches
+}
+
+// step applies a selector to the current nodes, or to them and all their descendants when recursive.
+type step struct {
+	recursive bool
+	sel       selector
+}
+
+type selector interface {
+	apply(node, root any, out []any) []any
+}
+
+func evalSteps(steps []step, node, root any) []any {
+	nodes := []any{node}
+	for _, s := range steps {
+		var next []any
+		for _, n := range nodes {
+			if s.recursive {
+				for _, d := range descendants(n, nil) {
+					next = s.sel.apply(d, root, next)
+				}
+				continue
+			}
+			next = s.sel.apply(n, root, next)
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// descendants returns node and everything below it, in document order with object keys sorted.
+func descendants(node any, out []any) []any {
+	out = append(out, node)
+	switch v := node.(type) {
+	case map[string]any:
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			out = descendants(v[key], out)
+		}
+	case []any:
+		for _, child := range v {
+			out = descendants(child, out)
+		}
+	}
+	return out
+}
+
+// children returns the values of an object, sorted by key, or the elements of an array.
+func children(node any) []any {
+	switch v := node.(type) {
+	case map[string]any:
+		values := make([]any, 0, len(v))
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			values = append(values, v[key])
+		}
+		return values
+	case []any:
+		return v
+	}
+	return nil
+}
+
+type nameSelector []string
+
+func (s nameSelector) apply(node, root any, out []any) []any {
+	if object, ok := node.(map[string]any); ok {
+		for _, name := range s {
+			if value, exists := object[name]; exists {
+				out = append(out, value)
+			}
+		}
+	}
+	return out
+}
+
+type wildcardSelector struct{}
+
+func (wildcardSelector) apply(node, root any, out []any) []any {
+	return append(out, children(node)...)
+}
+
+type indexSelector []int
+
+func (s indexSelector) apply(node, root any, out []any) []any {
+	if array, ok := node.([]any); ok {
+		for _, index := range s {
+			if index < 0 {
+				index += len(array)
+			}
+			if index >= 0 && index < len(array) {
+				out = append(out, array[index])
+			}
+		}
+	}
+	return out
+}
+
+type sliceSelector struct {
+	start, end, step *int
+}
+
+func (s sliceSelector) apply(node, root any, out []any) []any {
+	array, ok := node.([]any)
+	if !ok {
+		return out
+	}
+	n := len(array)
+	step := 1
+	if s.step != nil {
+		step = *s.step
+	}
+	if step == 0 {
+		return out
+	}
+	bound := func(value *int, fallback int) int {
+		if value == nil {
+			return fallback
+		}
+		i := *value
+		if i < 0 {
+			i += n
+		}
+		return min(max(i, -1), n)
+	}
+	if step > 0 {
+		for i := max(bound(s.start, 0), 0); i < bound(s.end, n); i += step {
+			out = append(out, array[i])
+		}
+		return out
+	}
+	for i := min(bound(s.start, n-1), n-1); i > bound(s.end, -1); i += step {
+		out = append(out, array[i])
+	}
+	return out
+}
+
+type filterSelector struct {
+	expr filterExpr
+}
+
+func (s filterSelector) apply(node, root any, out []any) []any {
+	for _, child := range children(node) {
+		if s.expr.test(child, root) {
+			out = append(out, child)
+		}
+	}
+	return out
+}
+
+type filterExpr interface {
+	test(item, root any) bool
+}
+
+type orExpr []filterExpr
+
+func (e orExpr) test(item, root any) bool {
+	for _, term := range e {
+		if term.test(item, root) {
+			return true
+		}
+	}
+	return false
+}
+
+type andExpr []filterExpr
+
+func (e andExpr) test(item, root any) bool {
+	for _, term := range e {
+		if !term.test(item, root) {
+			return false
+		}
+	}
+	return true
+}
+
+type notExpr struct {
+	expr filterExpr
+}
+
+func (e notExpr) test(item, root any) bool {
+	return !e.expr.test(item, root)
+}
+
+// existsExpr is true when its path matches something, or when its literal is truthy.
+type existsExpr struct {
+	operand operand
+}
+
+func (e existsExpr) test(item, root any) bool {
+	value, ok := e.operand.value(item, root)
+	if !ok {
+		return false
+	}
+	if e.operand.steps == nil {
+		return value != nil && value != false
+	}
+	return true
+}
+
+type compareExpr struct {
+	left, right operand
+	op          string
+}
+
+func (e compareExpr) test(item, root any) bool {
+	left, lok := e.left.value(item, root)
+	right, rok := e.right.value(item, root)
+	if !lok || !rok {
+		return e.op == "!=" && lok != rok
+	}
+
+	if l, ok := toNumber(left); ok {
+		if r, ok := toNumber(right); ok {
+			return compareOrdered(l, r, e.op)
+		}
+	}
+	if l, ok := left.(string); ok {
+		if r, ok := right.(string); ok {
+			return compareOrdered(l, r, e.op)
+		}
+	}
+	switch e.op {
+	case "==":
+		return reflect.DeepEqual(left, right)
+	case "!=":
+		return !reflect.DeepEqual(left, right)
+	}
+	return false
+}
+
+func compareOrdered[T float64 | string](l, r T, op string) bool {
+	switch op {
+	case "==":
+		return l == r
+	case "!=":
+		return l != r
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	case ">=":
+		return l >= r
+	}
+	return false
+}
+
+func toNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// operand is a literal or a path relative to the current item (@) or the root ($).
+type operand struct {
+	literal any
+	steps   []step
+	rooted  bool
+}
+
+// value returns the literal, or the first match of the path and whether there was one.
+func (o operand) value(item, root any) (any, bool) {
+	if o.steps == nil {
+		return o.literal, true
+	}
+	start := item
+	if o.rooted {
+		start = root
+	}
+	matches := evalSteps(o.steps, start, root)
+	if len(matches) == 0 {
+		return nil, false
+	}
+	return matches[0], true
+}
+
+// pathParser is a recursive descent parser over a path expression.
+type pathParser struct {
+	src string
+	pos int
+}
+
+func (p *pathParser) done() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *pathParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *pathParser) skipSpace() {
+	for !p.done() && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *pathParser) consume(token string) bool {
+	if strings.HasPrefix(p.src[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (p *pathParser) expect(token string) error {
+	p.skipSpace()
+	if !p.consume(token) {
+		return fmt.Errorf("expected %q at %d", token, p.pos)
+	}
+	return nil
+}
+
+// steps parses the child, descendant and bracket segments that follow $ or @.
+// It returns an empty, non-nil slice for a bare $ or @.
+func (p *pathParser) steps() ([]step, error) {
+	steps := []step{}
+	for {
+		recursive := false
+		switch {
+		case p.consume(".."):
+			recursive = true
+		case p.consume("."):
+		case p.peek() == '[':
+		default:
+			return steps, nil
+		}
+
+		var sel selector
+		var err error
+		switch {
+		case p.peek() == '[':
+			sel, err = p.bracket()
+		case p.consume("*"):
+			sel = wildcardSelector{}
+		default:
+			name := p.name()
+			if name == "" {
+				return nil, fmt.Errorf("expected name at %d", p.pos)
+			}
+			sel = nameSelector{name}
+		}
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step{recursive: recursive, sel: sel})
+	}
+}
+
+func (p *pathParser) name() string {
+	start := p.pos
+	for !p.done() && !strings.ContainsRune(".[]()'\" \t=!<>&|,", rune(p.src[p.pos])) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// bracket parses [*], ['a','b'], [0,1], [start:end:step] and [?filter].
+func (p *pathParser) bracket() (selector, error) {
+	p.pos++ // [
+	p.skipSpace()
+
+	var sel selector
+	switch c := p.peek(); {
+	case c == '*':
+		p.pos++
+		sel = wildcardSelector{}
+	case c == '?':
+		p.pos++
+		expr, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		sel = filterSelector{expr: expr}
+	case c == '\'' || c == '"':
+		var names nameSelector
+		for {
+			p.skipSpace()
+			name, err := p.quoted()
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, name)
+			p.skipSpace()
+			if !p.consume(",") {
+				break
+			}
+		}
+		sel = names
+	default:
+		var err error
+		if sel, err = p.indexes(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	return sel, nil
+}
+
+// indexes parses an index union such as 0,2,-1 or a slice such as 1:3 or ::-1.
+func (p *pathParser) indexes() (selector, error) {
+	first, err := p.optionalInt()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.peek() == ':' {
+		slice := sliceSelector{start: first}
+		p.pos++
+		if slice.end, err = p.optionalInt(); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.consume(":") {
+			if slice.step, err = p.optionalInt(); err != nil {
+				return nil, err
+			}
+		}
+		return slice, nil
+	}
+	if first == nil {
+		return nil, fmt.Errorf("expected index at %d", p.pos)
+	}
+
+	indexes := indexSelector{*first}
+	for {
+		p.skipSpace()
+		if !p.consume(",") {
+			return indexes, nil
+		}
+		next, err := p.optionalInt()
+		if err != nil {
+			return nil, err
+		}
+		if next == nil {
+			return nil, fmt.Errorf("expected index at %d", p.pos)
+		}
+		indexes = append(indexes, *next)
+	}
+}
+
+func (p *pathParser) optionalInt() (*int, error) {
+	p.skipSpace()
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	for !p.done() && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+		p.pos++
+	}
+	if p.pos == start {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(p.src[start:p.pos])
+	if err != nil {
+		return nil, fmt.Errorf("invalid index %q at %d", p.src[start:p.pos], start)
+	}
+	return &n, nil
+}
+
+func (p *pathParser) quoted() (string, error) {
+	quote := p.peek()
+	if quote != '\'' && quote != '"' {
+		return "", fmt.Errorf("expected quoted string at %d", p.pos)
+	}
+	var b strings.Builder
+	for p.pos++; !p.done(); p.pos++ {
+		c := p.src[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.src):
+			p.pos++
+			b.WriteByte(p.src[p.pos])
+		case c == quote:
+			p.pos++
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated string at %d", p.pos)
+}
+
+func (p *pathParser) or() (filterExpr, error) {
+	terms := orExpr{}
+	for {
+		term, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+		p.skipSpace()
+		if !p.consume("||") {
+			break
+		}
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return terms, nil
+}
+
+func (p *pathParser) and() (filterExpr, error) {
+	terms := andExpr{}
+	for {
+		term, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+		p.skipSpace()
+		if !p.consume("&&") {
+			break
+		}
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return terms, nil
+}
+
+func (p *pathParser) unary() (filterExpr, error) {
+	p.skipSpace()
+	if p.peek() == '!' && !strings.HasPrefix(p.src[p.pos:], "!=") {
+		p.pos++
+		expr, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr: expr}, nil
+	}
+	if p.consume("(") {
+		expr, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(op) {
+			right, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			return compareExpr{left: left, right: right, op: op}, nil
+		}
+	}
+	return existsExpr{operand: left}, nil
+}
+
+func (p *pathParser) operand() (operand, error) {
+	p.skipSpace()
+	switch c := p.peek(); {
+	case c == '@' || c == '$':
+		p.pos++
+		steps, err := p.steps()
+		if err != nil {
+			return operand{}, err
+		}
+		return operand{steps: steps, rooted: c == '$'}, nil
+	case c == '\'' || c == '"':
+		s, err := p.quoted()
+		return operand{literal: s}, err
+	case p.consume("true"):
+		return operand{literal: true}, nil
+	case p.consume("false"):
+		return operand{literal: false}, nil
+	case p.consume("null"):
+		return operand{literal: nil}, nil
+	}
+
+	start := p.pos
+	for !p.done() && strings.ContainsRune("+-0123456789.eE", rune(p.src[p.pos])) {
+		p.pos++
+	}
+	n, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+	if err != nil {
+		return operand{}, fmt.Errorf("expected value at %d", start)
+	}
+	return operand{literal: n}, nil
+}
@@ -0,0 +1,149 @@
+package fx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// requestSettings carries per-request configuration that does not live on *http.Request.
+type requestSettings struct {
+	err        error
+	retry      *RetryPolicy
+	timeout    time.Duration
+	limit      *rateLimit
+	cache      *cacheSettings
+	maxPages   int
+	prefetch   int
+	bodyLimit  int64
+	projection []*Path
+}
+
+type settingsKey struct{}
+
+// settingsOf returns the settings attached by createRequest, or detached settings for requests built elsewhere.
+func settingsOf(req *http.Request) *requestSettings {
+	if settings, ok := req.Context().Value(settingsKey{}).(*requestSettings); ok {
+		return settings
+	}
+	return &requestSettings{}
+}
+
+// FormFile is a file part of a multipart request body.
+type FormFile struct {
+	Field    string
+	Filename string
+	Content  io.Reader
+}
+
+// Send executes an Http request with the given method, URL and options and returns the response body
+func (f *Fx) Send(method, endpoint string, options ...GetOption) ([]byte, error) {
+	req, err := f.createRequest(method, endpoint, options...)
+	if err != nil {
+		return nil, err
+	}
+	return f.executeRequest(req)
+}
+
+func (f *Fx) Post(endpoint string, options ...GetOption) ([]byte, error) {
+	return f.Send(http.MethodPost, endpoint, options...)
+}
+
+func (f *Fx) Put(endpoint string, options ...GetOption) ([]byte, error) {
+	return f.Send(http.MethodPut, endpoint, options...)
+}
+
+func (f *Fx) Patch(endpoint string, options ...GetOption) ([]byte, error) {
+	return f.Send(http.MethodPatch, endpoint, options...)
+}
+
+func (f *Fx) Delete(endpoint string, options ...GetOption) ([]byte, error) {
+	return f.Send(http.MethodDelete, endpoint, options...)
+}
+
+// Fetch executes an Http request and decodes the JSON response into T. An empty body yields the zero value.
+func Fetch[T any](f *Fx, method, endpoint string, options ...GetOption) (T, error) {
+	var target T
+	body, err := f.Send(method, endpoint, options...)
+	if err != nil {
+		return target, err
+	}
+	if len(body) == 0 {
+		return target, nil
+	}
+	if err := json.Unmarshal(body, &target); err != nil {
+		return target, fmt.Errorf("failed to decode response from %s: %w", endpoint, err)
+	}
+	return target, nil
+}
+
+// Body option builders
+func WithBody(data []byte, contentType string) GetOption {
+	return func(req *http.Request) {
+		setBody(req, data, contentType)
+	}
+}
+
+func WithJSON(value any) GetOption {
+	return func(req *http.Request) {
+		data, err := json.Marshal(value)
+		if err != nil {
+			settingsOf(req).err = fmt.Errorf("failed to encode JSON body: %w", err)
+			return
+		}
+		setBody(req, data, "application/json")
+	}
+}
+
+func WithForm(values url.Values) GetOption {
+	return func(req *http.Request) {
+		setBody(req, []byte(values.Encode()), "application/x-www-form-urlencoded")
+	}
+}
+
+func WithMultipart(fields map[string]string, files ...FormFile) GetOption {
+	return func(req *http.Request) {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		if err := writeMultipart(writer, fields, files); err != nil {
+			settingsOf(req).err = fmt.Errorf("failed to encode multipart body: %w", err)
+			return
+		}
+		setBody(req, buf.Bytes(), writer.FormDataContentType())
+	}
+}
+
+func writeMultipart(writer *multipart.Writer, fields map[string]string, files []FormFile) error {
+	for key, value := range fields {
+		if err := writer.WriteField(key, value); err != nil {
+			return err
+		}
+	}
+	for _, file := range files {
+		part, err := writer.CreateFormFile(file.Field, file.Filename)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(part, file.Content); err != nil {
+			return fmt.Errorf("failed to read %s: %w", file.Filename, err)
+		}
+	}
+	return writer.Close()
+}
+
+// setBody installs a replayable body so the request can be sent more than once.
+func setBody(req *http.Request, data []byte, contentType string) {
+	req.ContentLength = int64(len(data))
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+}
@@ -0,0 +1,402 @@
+package fx
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/zachklingbeil/factory/zero"
+)
+
+// Problem is an error answered with its status as an application/problem+json body (RFC 9457).
+// Only Problem and StatusCoder errors reach clients, every other error is answered with a generic 500.
+type Problem struct {
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Err      error  `json:"-"` // cause, logged but never written
+}
+
+// NewProblem returns a Problem titled after status.
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{Title: http.StatusText(status), Status: status, Detail: detail}
+}
+
+// Problemf returns a Problem whose detail is formatted like fmt.Errorf, wrapping any %w error as its cause.
+// The cause is left out of the detail, so "query failed: %w" is answered as "query failed".
+func Problemf(status int, format string, args ...any) *Problem {
+	err := fmt.Errorf(format, args...)
+	detail := fmt.Sprintf(strings.ReplaceAll(format, "%w", "%.0s"), args...)
+	problem := NewProblem(status, strings.TrimRight(detail, ": "))
+	problem.Err = errors.Unwrap(err)
+	return problem
+}
+
+// Error describes the problem with its cause, for logs.
+func (p *Problem) Error() string {
+	message := fmt.Sprintf("%d %s", p.Status, p.Title)
+	if p.Detail != "" {
+		message += ": " + p.Detail
+	}
+	if p.Err != nil {
+		message += ": " + p.Err.Error()
+	}
+	return message
+}
+
+func (p *Problem) Unwrap() error {
+	return p.Err
+}
+
+// StatusCoder is implemented by domain errors that choose their Http status. Their message is sent as the problem detail.
+type StatusCoder interface {
+	error
+	StatusCode() int
+}
+
+// problemFor maps err to the Problem written to the client.
+func problemFor(err error) *Problem {
+	var problem *Problem
+	var coder StatusCoder
+	var httpErr *HTTPError
+	switch {
+	case errors.As(err, &problem):
+		return problem
+	case errors.As(err, &coder):
+		return NewProblem(coder.StatusCode(), coder.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return NewProblem(http.StatusGatewayTimeout, "")
+	case errors.Is(err, context.Canceled):
+		return &Problem{Title: "Client Closed Request", Status: 499}
+	case errors.As(err, &httpErr):
+		return NewProblem(http.StatusBadGateway, "upstream request failed")
+	default:
+		return NewProblem(http.StatusInternalServerError, "")
+	}
+}
+
+// WriteProblem answers with the Problem for err and logs the cause of server errors.
+func (f *Fx) WriteProblem(w http.ResponseWriter, r *http.Request, err error) {
+	problem := *problemFor(err)
+	if problem.Status == 0 {
+		problem.Status = http.StatusInternalServerError
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+	if problem.Instance == "" && r != nil {
+		problem.Instance = r.URL.Path
+	}
+	if problem.Status >= 500 {
+		log.Printf("%s: %v", problem.Instance, err)
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// RespondOption configures Respond.
+type RespondOption func(*respondConfig)
+
+type respondConfig struct {
+	locker   sync.Locker
+	template *template.Template
+	err      error
+	etag     bool
+	status   int
+}
+
+// WithLocker holds locker while the input function runs.
+func WithLocker(locker sync.Locker) RespondOption {
+	return func(c *respondConfig) {
+		c.locker = locker
+	}
+}
+
+// WithHTML offers text/html, rendering the data with tmpl as an html/template.
+func WithHTML(tmpl zero.One) RespondOption {
+	parsed, err := template.New("respond").Parse(string(tmpl))
+	return func(c *respondConfig) {
+		c.template, c.err = parsed, err
+	}
+}
+
+// WithETag sets a strong ETag from the encoded body and answers matching If-None-Match requests with 304.
+func WithETag() RespondOption {
+	return func(c *respondConfig) {
+		c.etag = true
+	}
+}
+
+// WithStatus answers successful responses with status instead of 200.
+func WithStatus(status int) RespondOption {
+	return func(c *respondConfig) {
+		c.status = status
+	}
+}
+
+// Media types offered by Respond.
+const (
+	mediaJSON   = "application/json"
+	mediaNDJSON = "application/x-ndjson"
+	mediaCSV    = "text/csv"
+	mediaHTML   = "text/html"
+)
+
+// Out writes single response for http requests as JSON, using a function to source data and an optional locker to synchronize access.
+// Errors are answered as application/problem+json, see Respond.
+func (f *Fx) Out(w http.ResponseWriter, input func() (any, error), locker sync.Locker) {
+	f.Respond(w, nil, input, WithLocker(locker))
+}
+
+// Respond writes the data from input in the format the request accepts: JSON, NDJSON, CSV or HTML when WithHTML is given.
+// Errors from input are answered as application/problem+json with the status of a Problem or StatusCoder, or 500.
+// A nil request answers JSON.
+func (f *Fx) Respond(w http.ResponseWriter, r *http.Request, input func() (any, error), options ...RespondOption) {
+	config := &respondConfig{status: http.StatusOK}
+	for _, option := range options {
+		option(config)
+	}
+	if config.err != nil {
+		f.WriteProblem(w, r, fmt.Errorf("invalid response template: %w", config.err))
+		return
+	}
+
+	offers := []string{mediaJSON, mediaNDJSON, mediaCSV}
+	if config.template != nil {
+		offers = append(offers, mediaHTML)
+	}
+	media := mediaJSON
+	if r != nil {
+		w.Header().Add("Vary", "Accept")
+		if media = negotiate(r.Header.Get("Accept"), offers); media == "" {
+			f.WriteProblem(w, r, NewProblem(http.StatusNotAcceptable, "supported types: "+strings.Join(offers, ", ")))
+			return
+		}
+	}
+
+	if config.locker != nil {
+		config.locker.Lock()
+	}
+	data, err := input()
+	if config.locker != nil {
+		config.locker.Unlock()
+	}
+	if err != nil {
+		f.WriteProblem(w, r, err)
+		return
+	}
+
+	body, contentType, err := f.encode(media, data, config.template)
+	if err != nil {
+		f.WriteProblem(w, r, fmt.Errorf("failed to encode %s response: %w", media, err))
+		return
+	}
+
+	if config.etag {
+		tag := etag(body)
+		w.Header().Set("ETag", tag)
+		if r != nil && etagMatches(r.Header.Get("If-None-Match"), tag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(config.status)
+	w.Write(body)
+}
+
+// encode renders data as media and returns the body and its Content-Type.
+func (f *Fx) encode(media string, data any, tmpl *template.Template) ([]byte, string, error) {
+	var buf bytes.Buffer
+	switch media {
+	case mediaNDJSON:
+		encoder := json.NewEncoder(&buf)
+		for _, item := range elements(data) {
+			if err := encoder.Encode(item); err != nil {
+				return nil, "", err
+			}
+		}
+		return buf.Bytes(), mediaNDJSON, nil
+	case mediaCSV:
+		if err := f.writeCSV(&buf, data); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "text/csv; charset=utf-8", nil
+	case mediaHTML:
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "text/html; charset=utf-8", nil
+	default:
+		if err := json.NewEncoder(&buf).Encode(data); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), mediaJSON, nil
+	}
+}
+
+// elements returns the items of a slice or array, or data itself.
+func elements(data any) []any {
+	v := reflect.ValueOf(data)
+	if data == nil || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Type().Elem().Kind() == reflect.Uint8 {
+		return []any{data}
+	}
+	items := make([]any, v.Len())
+	for i := range items {
+		items[i] = v.Index(i).Interface()
+	}
+	return items
+}
+
+// writeCSV writes [][]string as is, and any other data as one row per element flattened with Simplify.
+// The header row holds every flattened key in order of first appearance.
+func (f *Fx) writeCSV(buf *bytes.Buffer, data any) error {
+	writer := csv.NewWriter(buf)
+	if rows, ok := data.([][]string); ok {
+		if err := writer.WriteAll(rows); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	var header []string
+	columns := make(map[string]int)
+	var records []map[string]any
+	for _, item := range elements(data) {
+		record := make(map[string]any)
+		if fields, ok := f.Simplify(item, WithSorted()).(Fields); ok {
+			for _, field := range fields {
+				if _, seen := columns[field.Key]; !seen {
+					columns[field.Key] = len(header)
+					header = append(header, field.Key)
+				}
+				record[field.Key] = field.Value
+			}
+		} else if item != nil {
+			if _, seen := columns["value"]; !seen {
+				columns["value"] = len(header)
+				header = append(header, "value")
+			}
+			record["value"] = item
+		}
+		records = append(records, record)
+	}
+
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, record := range records {
+		row := make([]string, len(header))
+		for key, value := range record {
+			row[columns[key]] = csvValue(value)
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case encoding.TextMarshaler:
+		if text, err := v.MarshalText(); err == nil {
+			return string(text)
+		}
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(value)
+}
+
+// negotiate picks the offer with the highest quality in the Accept header, preferring earlier offers on ties.
+func negotiate(accept string, offers []string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q := quality(accept, offer)
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// quality returns the q value of the most specific Accept range matching offer.
+func quality(accept, offer string) float64 {
+	offerType, _, _ := strings.Cut(offer, "/")
+	q, specificity := 0.0, -1
+	for part := range strings.SplitSeq(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		rangeType, rangeSub, _ := strings.Cut(mediaType, "/")
+		var s int
+		switch {
+		case mediaType == offer || (offer == mediaNDJSON && mediaType == "application/ndjson"):
+			s = 2
+		case rangeSub == "*" && rangeType == offerType:
+			s = 1
+		case mediaType == "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s <= specificity {
+			continue
+		}
+		specificity, q = s, 1
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+	}
+	return q
+}
+
+func etag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header matches tag, comparing weakly as RFC 9110 requires.
+func etagMatches(header, tag string) bool {
+	for candidate := range strings.SplitSeq(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
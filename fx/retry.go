@@ -0,0 +1,219 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryPolicy retries requests that fail with a network error, 429 or 5xx using exponential backoff with jitter.
+type RetryPolicy struct {
+	Attempts  int           // total attempts including the first
+	BaseDelay time.Duration // delay before the second attempt, doubled for each one after, defaults to 200ms
+	MaxDelay  time.Duration // upper bound for any delay, including Retry-After, defaults to 30s
+}
+
+// WithRetry retries the request according to policy.
+func WithRetry(policy RetryPolicy) GetOption {
+	policy = policy.withDefaults(200 * time.Millisecond)
+	return func(req *http.Request) {
+		// Each request gets its own copy, so the option can be shared between concurrent requests.
+		retry := policy
+		settingsOf(req).retry = &retry
+	}
+}
+
+// WithTimeout bounds each attempt of the request, including reading its body.
+func WithTimeout(timeout time.Duration) GetOption {
+	return func(req *http.Request) {
+		settingsOf(req).timeout = timeout
+	}
+}
+
+// WithRateLimit limits requests to the request's host to perSecond with bursts of up to burst.
+// The first limit seen for a host applies to every later request to it.
+func WithRateLimit(perSecond float64, burst int) GetOption {
+	return func(req *http.Request) {
+		settingsOf(req).limit = &rateLimit{perSecond: perSecond, burst: burst}
+	}
+}
+
+// RateLimit limits every request to host to perSecond with bursts of up to burst, replacing any previous limit.
+func (f *Fx) RateLimit(host string, perSecond float64, burst int) {
+	f.limits.set(host, newBucket(rateLimit{perSecond: perSecond, burst: burst}))
+}
+
+// next reports whether a failed attempt should be retried and how long to wait first.
+func (p *RetryPolicy) next(req *http.Request, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if p == nil || attempt >= p.Attempts || req.Context().Err() != nil {
+		return 0, false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return 0, false
+	}
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return 0, false
+		}
+		return p.backoff(attempt), true
+	}
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+		return 0, false
+	}
+	if delay, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+		return min(delay, p.MaxDelay), true
+	}
+	return p.backoff(attempt), true
+}
+
+// withDefaults fills in an unset BaseDelay with base and an unset MaxDelay with 30s.
+func (p RetryPolicy) withDefaults(base time.Duration) RetryPolicy {
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = base
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = 30 * time.Second
+	}
+	return p
+}
+
+// backoff returns a delay between half and all of BaseDelay*2^(attempt-1), capped at MaxDelay.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.MaxDelay
+	if attempt < 32 {
+		delay = min(p.BaseDelay<<(attempt-1), p.MaxDelay)
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+type rateLimit struct {
+	perSecond float64
+	burst     int
+}
+
+// hostLimits holds one token bucket per host.
+type hostLimits struct {
+	sync.Mutex
+	buckets map[string]*bucket
+}
+
+func newHostLimits() *hostLimits {
+	return &hostLimits{buckets: make(map[string]*bucket)}
+}
+
+func (h *hostLimits) set(host string, b *bucket) {
+	h.Lock()
+	defer h.Unlock()
+	h.buckets[host] = b
+}
+
+// forRequest returns the bucket for host, creating it from the request's limit when there is none yet.
+func (h *hostLimits) forRequest(host string, settings *requestSettings) *bucket {
+	h.Lock()
+	defer h.Unlock()
+	if b, exists := h.buckets[host]; exists {
+		return b
+	}
+	if settings.limit == nil {
+		return nil
+	}
+	b := newBucket(*settings.limit)
+	h.buckets[host] = b
+	return b
+}
+
+// prune drops buckets untouched for longer than idle, which have refilled to their burst.
+func (h *hostLimits) prune(idle time.Duration) {
+	h.Lock()
+	defer h.Unlock()
+	now := time.Now()
+	for host, b := range h.buckets {
+		b.Lock()
+		stale := now.Sub(b.last) > idle
+		b.Unlock()
+		if stale {
+			delete(h.buckets, host)
+		}
+	}
+}
+
+// bucket is a token bucket refilled continuously at rate tokens per second.
+type bucket struct {
+	sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(limit rateLimit) *bucket {
+	burst := float64(max(limit.burst, 1))
+	return &bucket{rate: limit.perSecond, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// take takes n tokens when they are available without waiting.
+func (b *bucket) take(n float64) bool {
+	if b.rate <= 0 {
+		return true
+	}
+	b.Lock()
+	defer b.Unlock()
+	b.refill(time.Now())
+	if b.tokens < n {
+		return false
+	}
+	b.tokens -= n
+	return true
+}
+
+func (b *bucket) refill(now time.Time) {
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// wait takes a token, sleeping until one is available or ctx is done.
+func (b *bucket) wait(ctx context.Context) error {
+	if b.rate <= 0 {
+		return nil
+	}
+
+	b.Lock()
+	b.refill(time.Now())
+	b.tokens--
+	deficit := -b.tokens
+	b.Unlock()
+
+	if deficit <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(time.Duration(deficit / b.rate * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		b.Lock()
+		b.tokens++
+		b.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,193 @@
+package fx
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ProxyStats counts lookups of cacheable methods in the GethHandler response cache.
+type ProxyStats struct {
+	CacheHits   uint64 `json:"cache_hits"`
+	CacheMisses uint64 `json:"cache_misses"`
+}
+
+// HitRatio returns the share of lookups served from the cache, 0 before the first lookup.
+func (s ProxyStats) HitRatio() float64 {
+	total := s.CacheHits + s.CacheMisses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.CacheHits) / float64(total)
+}
+
+// ProxyStats returns the response cache counters of GethHandler since the last ConfigureProxy.
+func (f *Fx) ProxyStats() ProxyStats {
+	return ProxyStats{CacheHits: f.proxy.hits.Load(), CacheMisses: f.proxy.misses.Load()}
+}
+
+// immutableMethods lists the methods whose results never change once the block they belong to is finalized.
+// Chain-wide constants belong to no block and are always final.
+var immutableMethods = map[string]bool{
+	"eth_chainId":                             true,
+	"net_version":                             true,
+	"eth_getBlockByNumber":                    true,
+	"eth_getBlockByHash":                      true,
+	"eth_getBlockReceipts":                    true,
+	"eth_getTransactionByHash":                true,
+	"eth_getTransactionReceipt":               true,
+	"eth_getTransactionByBlockHashAndIndex":   true,
+	"eth_getTransactionByBlockNumberAndIndex": true,
+}
+
+// cacheable reports whether call is an immutable method addressing its block by number or hash rather than by a tag such as latest.
+func cacheable(call *rpcCall) bool {
+	if !immutableMethods[call.method] {
+		return false
+	}
+	for _, param := range call.params {
+		var tag string
+		if raw, ok := param.(json.RawMessage); ok && json.Unmarshal(raw, &tag) == nil && !strings.HasPrefix(tag, "0x") {
+			return false
+		}
+	}
+	return true
+}
+
+// rpcCache stores final results of the immutable methods and tracks the finalized block.
+type rpcCache struct {
+	store CacheStore
+	ttl   time.Duration
+
+	sync.Mutex
+	finalized  uint64
+	checked    time.Time
+	refreshing bool
+}
+
+// lookup fills the results of cached calls, counting a hit or miss for every cacheable call.
+func (p *proxy) lookup(ctx context.Context, chainID string, calls []*rpcCall) {
+	for _, call := range calls {
+		if !cacheable(call) {
+			continue
+		}
+		data, ok, err := p.cache.store.Get(ctx, rpcCacheKey(chainID, call))
+		if err == nil && ok {
+			call.result, call.cached = data, true
+			p.hits.Add(1)
+			continue
+		}
+		p.misses.Add(1)
+	}
+}
+
+// remember stores the results of forwarded calls that belong to a finalized block.
+func (p *proxy) remember(ctx context.Context, client *rpc.Client, chainID string, calls []*rpcCall) {
+	for _, call := range calls {
+		if call.cached || call.err != nil || !cacheable(call) {
+			continue
+		}
+		if !p.cache.final(ctx, client, call) {
+			continue
+		}
+		_ = p.cache.store.Set(ctx, rpcCacheKey(chainID, call), call.result, p.cache.ttl)
+	}
+}
+
+// final reports whether the result of call can no longer change. Results are final when they belong to no block,
+// or to a block at or below the finalized one. Null results, pending data and nodes without finality are never final.
+func (c *rpcCache) final(ctx context.Context, client *rpc.Client, call *rpcCall) bool {
+	if call.method == "eth_chainId" || call.method == "net_version" {
+		return true
+	}
+	number, ok := resultBlock(call.result)
+	if !ok {
+		return false
+	}
+	finalized, ok := c.finalizedBlock(ctx, client)
+	return ok && number <= finalized
+}
+
+// finalizedBlock returns the finalized block number, asking the node at most once per slot. A single caller refreshes
+// it without holding the lock, the others meanwhile use the number known so far.
+func (c *rpcCache) finalizedBlock(ctx context.Context, client *rpc.Client) (uint64, bool) {
+	c.Lock()
+	finalized := c.finalized
+	if c.refreshing || time.Since(c.checked) < 12*time.Second {
+		c.Unlock()
+		return finalized, finalized > 0
+	}
+	c.refreshing = true
+	c.Unlock()
+
+	var head struct {
+		Number string `json:"number"`
+	}
+	err := client.CallContext(ctx, &head, "eth_getBlockByNumber", "finalized", false)
+
+	c.Lock()
+	defer c.Unlock()
+	c.refreshing = false
+	if err != nil {
+		return c.finalized, c.finalized > 0
+	}
+	if number, err := strconv.ParseUint(strings.TrimPrefix(head.Number, "0x"), 16, 64); err == nil {
+		c.finalized = number
+	}
+	c.checked = time.Now()
+	return c.finalized, c.finalized > 0
+}
+
+// resultBlock returns the block a block, transaction or receipt result belongs to, using the first element of a list.
+func resultBlock(result json.RawMessage) (uint64, bool) {
+	result = bytes.TrimSpace(result)
+	if len(result) > 0 && result[0] == '[' {
+		var list []json.RawMessage
+		if json.Unmarshal(result, &list) != nil || len(list) == 0 {
+			return 0, false
+		}
+		result = list[0]
+	}
+
+	var fields struct {
+		Number      *string `json:"number"`
+		BlockNumber *string `json:"blockNumber"`
+	}
+	if json.Unmarshal(result, &fields) != nil {
+		return 0, false
+	}
+	block := fields.BlockNumber
+	if block == nil {
+		block = fields.Number
+	}
+	if block == nil {
+		return 0, false
+	}
+	number, err := strconv.ParseUint(strings.TrimPrefix(*block, "0x"), 16, 64)
+	return number, err == nil
+}
+
+// rpcCacheKey identifies a call by chain, method and compacted params.
+func rpcCacheKey(chainID string, call *rpcCall) string {
+	hash := sha256.New()
+	hash.Write([]byte(chainID + "\n" + call.method + "\n"))
+	for _, param := range call.params {
+		var compact bytes.Buffer
+		if raw, ok := param.(json.RawMessage); ok && json.Compact(&compact, raw) == nil {
+			hash.Write(compact.Bytes())
+		} else {
+			data, _ := json.Marshal(param)
+			hash.Write(data)
+		}
+		hash.Write([]byte{'\n'})
+	}
+	return "fx:rpc:" + hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,117 @@
+package fx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+)
+
+// ErrBodyTooLarge is returned while reading a response body longer than WithBodyLimit allows.
+var ErrBodyTooLarge = errors.New("response body exceeds limit")
+
+// WithBodyLimit fails reading the response with ErrBodyTooLarge once the body exceeds limit bytes.
+func WithBodyLimit(limit int64) GetOption {
+	return func(req *http.Request) {
+		settingsOf(req).bodyLimit = limit
+	}
+}
+
+// Stream executes an Http request and decodes the elements of a top-level JSON array response one at a time,
+// without buffering the body. Iteration stops after the first error.
+func Stream[T any](f *Fx, method, endpoint string, options ...GetOption) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		resp, err := f.open(method, endpoint, options...)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer resp.Body.Close()
+
+		decoder := json.NewDecoder(resp.Body)
+		if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+			yield(zero, fmt.Errorf("response from %s is not a JSON array: %v", endpoint, errOr(err, token)))
+			return
+		}
+		for decoder.More() {
+			var element T
+			if err := decoder.Decode(&element); err != nil {
+				yield(zero, fmt.Errorf("failed to decode element from %s: %w", endpoint, err))
+				return
+			}
+			if !yield(element, nil) {
+				return
+			}
+		}
+		if _, err := decoder.Token(); err != nil {
+			yield(zero, fmt.Errorf("failed to read end of array from %s: %w", endpoint, err))
+		}
+	}
+}
+
+// StreamLines executes an Http request and decodes a newline delimited JSON (NDJSON) response one value at a time,
+// without buffering the body. Iteration stops after the first error.
+func StreamLines[T any](f *Fx, method, endpoint string, options ...GetOption) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		resp, err := f.open(method, endpoint, options...)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer resp.Body.Close()
+
+		decoder := json.NewDecoder(resp.Body)
+		for decoder.More() {
+			var value T
+			if err := decoder.Decode(&value); err != nil {
+				yield(zero, fmt.Errorf("failed to decode line from %s: %w", endpoint, err))
+				return
+			}
+			if !yield(value, nil) {
+				return
+			}
+		}
+	}
+}
+
+// open executes an Http request and returns the response with its body unread. The caller closes the body.
+func (f *Fx) open(method, endpoint string, options ...GetOption) (*http.Response, error) {
+	req, err := f.createRequest(method, endpoint, options...)
+	if err != nil {
+		return nil, err
+	}
+	return f.do(req)
+}
+
+// limitedBody reads at most remaining bytes and fails instead of truncating.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, ErrBodyTooLarge
+	}
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		n, b.remaining = int(b.remaining), -1
+		return n, ErrBodyTooLarge
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
+
+func errOr(err error, token json.Token) any {
+	if err != nil {
+		return err
+	}
+	return token
+}
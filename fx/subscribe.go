@@ -0,0 +1,450 @@
+package fx
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gorilla/websocket"
+)
+
+// Keepalive timing for WebSocket clients.
+const (
+	wsPingInterval = 30 * time.Second
+	wsReadTimeout  = 60 * time.Second
+	wsWriteTimeout = 10 * time.Second
+)
+
+// SubscriptionHandler serves JSON-RPC over WebSocket. Clients subscribing to the same eth_subscribe filter share one
+// upstream subscription, which is cancelled when its last client unsubscribes or disconnects. Other calls are proxied
+// like GethHandler, with the same method lists, rate limits and origins. The upstream endpoint must be ipc:// or ws(s)://.
+func (f *Fx) SubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	p := f.proxy
+	upgrader := websocket.Upgrader{CheckOrigin: p.checkOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := &wsClient{
+		conn:  conn,
+		send:  make(chan []byte, 256),
+		done:  make(chan struct{}),
+		feeds: make(map[string]*feed),
+	}
+	go c.writeLoop()
+	defer func() {
+		f.feeds.leave(c)
+		c.close()
+	}()
+
+	conn.SetReadLimit(p.config.MaxBody)
+	conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+	})
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		f.handleMessage(p, c, r, message)
+	}
+}
+
+// handleMessage answers one WebSocket message, a single call or a batch, and starts the subscriptions it created.
+func (f *Fx) handleMessage(p *proxy, c *wsClient, r *http.Request, message []byte) {
+	calls, batch, failure := parseCalls(message)
+	if failure != nil {
+		c.write(rpcResponse{Error: failure})
+		return
+	}
+	if batch && p.config.MaxBatch > 0 && len(calls) > p.config.MaxBatch {
+		c.write(rpcResponse{Error: &rpcError{Code: rpcLimitExceeded, Message: "batch too large"}})
+		return
+	}
+	if !p.allowClient(r, len(calls)) {
+		c.write(rpcResponse{Error: &rpcError{Code: rpcLimitExceeded, Message: "rate limit exceeded"}})
+		return
+	}
+	p.checkMethods(calls)
+
+	var forwarded []*rpcCall
+	var started []func()
+	for _, call := range calls {
+		switch {
+		case call.err != nil:
+		case call.method == "eth_subscribe":
+			if start := f.feeds.subscribe(f, p, c, call); start != nil {
+				started = append(started, start)
+			}
+		case call.method == "eth_unsubscribe":
+			f.feeds.unsubscribe(c, call)
+		default:
+			forwarded = append(forwarded, call)
+		}
+	}
+	if len(forwarded) > 0 {
+		if client, _, err := f.EthClients(); err != nil {
+			for _, call := range forwarded {
+				call.err = &rpcError{Code: rpcServerError, Message: "ethereum client unavailable"}
+			}
+		} else {
+			ctx, cancel := context.WithTimeout(f.Context, p.config.Timeout)
+			p.forward(ctx, client, f.chainID(), forwarded)
+			cancel()
+		}
+	}
+
+	// Notifications follow the response carrying their subscription id.
+	responses := replies(calls)
+	switch {
+	case len(responses) == 0:
+	case batch:
+		c.write(responses)
+	default:
+		c.write(responses[0])
+	}
+	for _, start := range started {
+		start()
+	}
+}
+
+// checkOrigin accepts clients without an Origin header, the configured origins and, without any, the same host.
+func (p *proxy) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || p.allowOrigin(origin) {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && len(p.config.Origins) == 0 && u.Host == r.Host
+}
+
+// wsClient is one WebSocket connection. Writes are queued so a slow client cannot hold up the others, and a client
+// whose queue fills is disconnected.
+type wsClient struct {
+	conn      *websocket.Conn
+	send      chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+	feeds     map[string]*feed // by client subscription id, guarded by the feeds lock
+}
+
+func (c *wsClient) write(message any) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Failed to encode WebSocket message: %v", err)
+		return
+	}
+	select {
+	case c.send <- data:
+	case <-c.done:
+	default:
+		c.close()
+	}
+}
+
+func (c *wsClient) writeLoop() {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case data := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				c.close()
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				c.close()
+				return
+			}
+		}
+	}
+}
+
+func (c *wsClient) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+// subscriptionNotification is the message carrying one subscription result to a client.
+type subscriptionNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  struct {
+		Subscription string          `json:"subscription"`
+		Result       json.RawMessage `json:"result"`
+	} `json:"params"`
+}
+
+// feeds shares upstream subscriptions between clients, keyed by their eth_subscribe params.
+type feeds struct {
+	sync.Mutex
+	byKey map[string]*feed
+}
+
+func newFeeds() *feeds {
+	return &feeds{byKey: make(map[string]*feed)}
+}
+
+// feed is one upstream subscription and the client subscriptions it fans out to.
+type feed struct {
+	key         string
+	params      []any
+	upstream    *rpc.ClientSubscription
+	results     chan json.RawMessage
+	subscribers map[string]*subscriber // by client subscription id
+	closed      bool
+}
+
+type subscriber struct {
+	client *wsClient
+	active bool
+}
+
+// subscribe joins or creates the feed for call and sets its result to a new subscription id.
+// The returned function starts delivering notifications, after the client has received the id.
+func (s *feeds) subscribe(f *Fx, p *proxy, c *wsClient, call *rpcCall) func() {
+	if len(call.params) == 0 {
+		call.err = &rpcError{Code: rpcInvalidParams, Message: "missing subscription name"}
+		return nil
+	}
+	s.Lock()
+	count := len(c.feeds)
+	s.Unlock()
+	if p.config.MaxSubscriptions > 0 && count >= p.config.MaxSubscriptions {
+		call.err = &rpcError{Code: rpcLimitExceeded, Message: "too many subscriptions"}
+		return nil
+	}
+
+	key := subscriptionKey(call.params)
+	id := subscriptionID()
+	sub := &subscriber{client: c}
+	for attached := false; !attached; {
+		s.Lock()
+		if fd, exists := s.byKey[key]; exists {
+			fd.subscribers[id] = sub
+			c.feeds[id] = fd
+			attached = true
+		}
+		s.Unlock()
+		if attached {
+			break
+		}
+
+		fd, err := f.openFeed(p, key, call.params)
+		if err != nil {
+			call.err = err
+			return nil
+		}
+		s.Lock()
+		// Another client may have opened the same feed meanwhile, then join theirs.
+		if _, exists := s.byKey[key]; !exists {
+			s.byKey[key] = fd
+			fd.subscribers[id] = sub
+			c.feeds[id] = fd
+			attached = true
+		}
+		s.Unlock()
+		if attached {
+			go f.runFeed(fd, fd.upstream)
+		} else {
+			fd.upstream.Unsubscribe()
+		}
+	}
+
+	call.result, _ = json.Marshal(id)
+	return func() {
+		s.Lock()
+		sub.active = true
+		s.Unlock()
+	}
+}
+
+// unsubscribe removes the client subscription named by call, cancelling the upstream one when nobody else uses it.
+func (s *feeds) unsubscribe(c *wsClient, call *rpcCall) {
+	var id string
+	if len(call.params) > 0 {
+		if raw, ok := call.params[0].(json.RawMessage); ok {
+			json.Unmarshal(raw, &id)
+		}
+	}
+	s.Lock()
+	fd, exists := c.feeds[id]
+	var cancel *rpc.ClientSubscription
+	if exists {
+		cancel = s.remove(fd, id, c)
+	}
+	s.Unlock()
+	if !exists {
+		call.err = &rpcError{Code: rpcServerError, Message: "subscription not found"}
+		return
+	}
+	if cancel != nil {
+		cancel.Unsubscribe()
+	}
+	call.result = json.RawMessage("true")
+}
+
+// leave removes every subscription of a disconnected client.
+func (s *feeds) leave(c *wsClient) {
+	var cancels []*rpc.ClientSubscription
+	s.Lock()
+	for id, fd := range c.feeds {
+		if cancel := s.remove(fd, id, c); cancel != nil {
+			cancels = append(cancels, cancel)
+		}
+	}
+	s.Unlock()
+	for _, cancel := range cancels {
+		cancel.Unsubscribe()
+	}
+}
+
+// remove detaches subscription id from fd and returns the upstream subscription to cancel when fd has no subscribers left.
+// The caller holds the lock.
+func (s *feeds) remove(fd *feed, id string, c *wsClient) *rpc.ClientSubscription {
+	delete(fd.subscribers, id)
+	delete(c.feeds, id)
+	if len(fd.subscribers) > 0 {
+		return nil
+	}
+	fd.closed = true
+	delete(s.byKey, fd.key)
+	return fd.upstream
+}
+
+// openFeed subscribes upstream with params.
+func (f *Fx) openFeed(p *proxy, key string, params []any) (*feed, *rpcError) {
+	client, _, err := f.EthClients()
+	if err != nil {
+		return nil, &rpcError{Code: rpcServerError, Message: "ethereum client unavailable"}
+	}
+	ctx, cancel := context.WithTimeout(f.Context, p.config.Timeout)
+	defer cancel()
+
+	fd := &feed{key: key, params: params, results: make(chan json.RawMessage, 64), subscribers: make(map[string]*subscriber)}
+	fd.upstream, err = client.Subscribe(ctx, "eth", fd.results, params...)
+	if err != nil {
+		if errors.Is(err, rpc.ErrNotificationsUnsupported) {
+			return nil, &rpcError{Code: rpcMethodNotFound, Message: "subscriptions are not available"}
+		}
+		return nil, upstreamError(err)
+	}
+	return fd, nil
+}
+
+// runFeed fans notifications out to the active subscribers until the feed closes.
+func (f *Fx) runFeed(fd *feed, upstream *rpc.ClientSubscription) {
+	for upstream != nil {
+		err := f.pump(fd, upstream)
+		if err == nil {
+			return
+		}
+		log.Printf("Ethereum subscription %s failed, resubscribing: %v", fd.params[0], err)
+		upstream = f.resubscribe(fd)
+	}
+}
+
+// resubscribe replaces a failed upstream subscription, for example after a failover, retrying with backoff.
+// It returns nil once the feed closes or the Fx context ends.
+func (f *Fx) resubscribe(fd *feed) *rpc.ClientSubscription {
+	s := f.feeds
+	retry := RetryPolicy{BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-f.Context.Done():
+			return nil
+		case <-time.After(retry.backoff(attempt)):
+		}
+		s.Lock()
+		closed := fd.closed
+		s.Unlock()
+		if closed {
+			return nil
+		}
+
+		client, _, err := f.EthClients()
+		if err != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(f.Context, 10*time.Second)
+		upstream, err := client.Subscribe(ctx, "eth", fd.results, fd.params...)
+		cancel()
+		if err != nil {
+			continue
+		}
+
+		s.Lock()
+		if fd.closed {
+			s.Unlock()
+			upstream.Unsubscribe()
+			return nil
+		}
+		fd.upstream = upstream
+		s.Unlock()
+		return upstream
+	}
+}
+
+// pump delivers notifications until upstream ends, returning nil when it was unsubscribed.
+func (f *Fx) pump(fd *feed, upstream *rpc.ClientSubscription) error {
+	s := f.feeds
+	for {
+		select {
+		case err := <-upstream.Err():
+			return err
+		case result := <-fd.results:
+			s.Lock()
+			var targets []*wsClient
+			var ids []string
+			for id, sub := range fd.subscribers {
+				if sub.active {
+					targets = append(targets, sub.client)
+					ids = append(ids, id)
+				}
+			}
+			s.Unlock()
+
+			for i, c := range targets {
+				var note subscriptionNotification
+				note.JSONRPC, note.Method = "2.0", "eth_subscription"
+				note.Params.Subscription, note.Params.Result = ids[i], result
+				c.write(note)
+			}
+		}
+	}
+}
+
+// subscriptionKey identifies a filter by its compacted params.
+func subscriptionKey(params []any) string {
+	var key bytes.Buffer
+	for _, param := range params {
+		if raw, ok := param.(json.RawMessage); ok && json.Compact(&key, raw) == nil {
+			key.WriteByte('\n')
+		}
+	}
+	return key.String()
+}
+
+// subscriptionID returns a random id in the format go-ethereum uses.
+func subscriptionID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return "0x" + hex.EncodeToString(id)
+}
@@ -0,0 +1,233 @@
+package fx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// redacted lists the headers whose values are never logged or recorded.
+var redacted = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// roundTripper adapts a function to http.RoundTripper.
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (rt roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return rt(req)
+}
+
+// LogTransport logs the method, URL, status, latency and headers of each exchange for use with WithTransportWrapper.
+// Authorization, cookies, API keys and the extra redact headers are logged as REDACTED. A nil logger uses log.Default.
+func LogTransport(logger *log.Logger, redact ...string) func(http.RoundTripper) http.RoundTripper {
+	if logger == nil {
+		logger = log.Default()
+	}
+	hidden := append(slices.Clone(redacted), redact...)
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripper(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			latency := time.Since(start).Round(time.Millisecond)
+			if err != nil {
+				logger.Printf("%s %s failed after %v: %v request=%s", req.Method, req.URL, latency, err, formatHeader(req.Header, hidden))
+				return nil, err
+			}
+			logger.Printf("%s %s %d %v request=%s response=%s", req.Method, req.URL, resp.StatusCode, latency,
+				formatHeader(req.Header, hidden), formatHeader(resp.Header, hidden))
+			return resp, nil
+		})
+	}
+}
+
+// formatHeader renders header in sorted order with hidden values replaced.
+func formatHeader(header http.Header, hidden []string) string {
+	keys := slices.Sorted(maps.Keys(header))
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := strings.Join(header[key], ", ")
+		if isHidden(key, hidden) {
+			value = "REDACTED"
+		}
+		parts = append(parts, key+": "+value)
+	}
+	return "[" + strings.Join(parts, "; ") + "]"
+}
+
+func isHidden(key string, hidden []string) bool {
+	return slices.ContainsFunc(hidden, func(h string) bool {
+		return strings.EqualFold(h, key)
+	})
+}
+
+// RecordMode selects how Recorder treats exchanges.
+type RecordMode int
+
+const (
+	// Record sends every request upstream and writes the exchange to disk.
+	Record RecordMode = iota
+	// Replay answers every request from disk and fails for requests that were never recorded.
+	Replay
+	// ReplayOrRecord answers from disk when a fixture exists and records the request otherwise.
+	ReplayOrRecord
+)
+
+// Recorder writes exchanges to dir as JSON fixtures and replays them, so Fx.In based code can run offline.
+// Fixtures are keyed by method, URL and request body, and never contain redacted header values.
+func Recorder(dir string, mode RecordMode) func(http.RoundTripper) http.RoundTripper {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripper(func(req *http.Request) (*http.Response, error) {
+			body, err := drainBody(req)
+			if err != nil {
+				return nil, err
+			}
+			path := filepath.Join(dir, fixtureName(req, body))
+
+			if mode != Record {
+				fixture, err := readFixture(path)
+				if err == nil {
+					return fixture.response(req), nil
+				}
+				if mode == Replay || !os.IsNotExist(err) {
+					return nil, fmt.Errorf("no recorded response for %s %s: %w", req.Method, req.URL, err)
+				}
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				return nil, err
+			}
+			respBody, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read response to record: %w", err)
+			}
+			resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+			fixture := &fixture{
+				Request:  newRecordedMessage(req.Header, body),
+				Response: newRecordedMessage(resp.Header, respBody),
+			}
+			fixture.Request.Method = req.Method
+			fixture.Request.URL = req.URL.String()
+			fixture.Response.Status = resp.StatusCode
+			if err := writeFixture(path, fixture); err != nil {
+				return nil, err
+			}
+			return resp, nil
+		})
+	}
+}
+
+// fixture is one recorded exchange.
+type fixture struct {
+	Request  recordedMessage `json:"request"`
+	Response recordedMessage `json:"response"`
+}
+
+type recordedMessage struct {
+	Method string      `json:"method,omitempty"`
+	URL    string      `json:"url,omitempty"`
+	Status int         `json:"status,omitempty"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+	Base64 bool        `json:"base64,omitempty"` // Body is base64 encoded binary
+}
+
+func newRecordedMessage(header http.Header, body []byte) recordedMessage {
+	message := recordedMessage{Header: header.Clone()}
+	for key := range message.Header {
+		if isHidden(key, redacted) {
+			message.Header[key] = []string{"REDACTED"}
+		}
+	}
+	if utf8.Valid(body) {
+		message.Body = string(body)
+	} else {
+		message.Body = base64.StdEncoding.EncodeToString(body)
+		message.Base64 = true
+	}
+	return message
+}
+
+func (m recordedMessage) body() []byte {
+	if m.Base64 {
+		data, _ := base64.StdEncoding.DecodeString(m.Body)
+		return data
+	}
+	return []byte(m.Body)
+}
+
+func (f *fixture) response(req *http.Request) *http.Response {
+	body := f.Response.body()
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Response.Status, http.StatusText(f.Response.Status)),
+		StatusCode:    f.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        f.Response.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// drainBody reads the request body and replaces it so the request can still be sent.
+func drainBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request to record: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func fixtureName(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n", req.Method, req.URL.String())
+	hash.Write(body)
+	return strings.ToLower(req.Method) + "-" + hex.EncodeToString(hash.Sum(nil))[:16] + ".json"
+}
+
+func readFixture(path string) (*fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fixture fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+	return &fixture, nil
+}
+
+func writeFixture(path string, fixture *fixture) error {
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode fixture: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write fixture %s: %w", path, err)
+	}
+	return nil
+}
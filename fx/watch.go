@@ -0,0 +1,292 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/zachklingbeil/factory/zero"
+)
+
+// Event is a contract event decoded by Watch. Args holds the indexed and data arguments by name, arguments without
+// one are named arg0, arg1 and so on. When a reorg drops the event it is delivered again with Log.Removed set.
+type Event struct {
+	Name string
+	Args map[string]any
+	Log  types.Log
+}
+
+// WatchOption configures Watch.
+type WatchOption func(*watchConfig)
+
+type watchConfig struct {
+	from *uint64 // nil follows new events only
+	poll time.Duration
+}
+
+// WithFromBlock replays the events from block number on before following new ones. Defaults to new events only.
+func WithFromBlock(number uint64) WatchOption {
+	return func(c *watchConfig) {
+		c.from = &number
+	}
+}
+
+// WithWatchInterval sets how often logs are polled when the endpoint cannot push them. Defaults to 12s.
+func WithWatchInterval(interval time.Duration) WatchOption {
+	return func(c *watchConfig) {
+		c.poll = interval
+	}
+}
+
+// Watch decodes the events matching signature, such as "Transfer(address indexed from, address indexed to, uint256 value)",
+// emitted by address and passes each to apply together with Zero, for example to Increment a counter viewers observe.
+// Logs are pushed over a log subscription when the endpoint supports one and polled otherwise.
+// Watch returns once the signature is parsed and the start block known, watching continues until ctx ends.
+func (f *Fx) Watch(ctx context.Context, address common.Address, signature string, apply func(z *zero.Zero, e Event), options ...WatchOption) error {
+	config := &watchConfig{poll: 12 * time.Second}
+	for _, option := range options {
+		option(config)
+	}
+	event, err := parseEvent(signature)
+	if err != nil {
+		return err
+	}
+
+	var next uint64
+	if config.from != nil {
+		next = *config.from
+	} else {
+		_, eth, err := f.EthClients()
+		if err != nil {
+			return err
+		}
+		head, err := eth.BlockNumber(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read the head block: %w", err)
+		}
+		next = head + 1
+	}
+
+	w := &watcher{
+		f:      f,
+		event:  event,
+		apply:  apply,
+		config: config,
+		query:  ethereum.FilterQuery{Addresses: []common.Address{address}, Topics: [][]common.Hash{{event.ID}}},
+		next:   next,
+		seen:   make(map[logID]uint64),
+	}
+	go w.run(ctx)
+	return nil
+}
+
+// watcher follows one event, delivering every log once in chain order.
+type watcher struct {
+	f      *Fx
+	event  abi.Event
+	apply  func(z *zero.Zero, e Event)
+	config *watchConfig
+	query  ethereum.FilterQuery
+	next   uint64 // first block a poll requests
+
+	seen    map[logID]uint64 // delivered logs by id, with their block number
+	highest uint64           // highest block delivered
+}
+
+// logID identifies a log in one block, so a log emitted again in the replacement of a reorged block is new.
+type logID struct {
+	block common.Hash
+	index uint
+}
+
+// watchDepth is how many blocks below the highest delivered one logs are remembered for deduplication.
+const watchDepth = 128
+
+// run subscribes to the logs, polling to catch up after each new subscription and while there is none.
+func (w *watcher) run(ctx context.Context) {
+	logs := make(chan types.Log, 64)
+	var sub ethereum.Subscription
+	defer func() {
+		if sub != nil {
+			sub.Unsubscribe()
+		}
+	}()
+	ticker := time.NewTicker(w.config.poll)
+	defer ticker.Stop()
+
+	for {
+		subscribed := false
+		if sub == nil {
+			if _, eth, err := w.f.EthClients(); err == nil {
+				if sub, err = eth.SubscribeFilterLogs(ctx, w.query, logs); err != nil {
+					sub = nil
+				}
+				subscribed = sub != nil
+			}
+		}
+		// Logs emitted before the subscription started are polled once it runs.
+		if sub == nil || subscribed {
+			if err := w.poll(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Failed to poll %s events, retrying: %v", w.event.Name, err)
+			}
+		}
+		var subErr <-chan error
+		if sub != nil {
+			subErr = sub.Err()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case entry := <-logs:
+			w.deliver(entry)
+		case <-ticker.C:
+		case <-subErr:
+			sub = nil
+		}
+	}
+}
+
+// poll delivers the logs from the next unpolled block up to the head, at most 2000 blocks per request.
+func (w *watcher) poll(ctx context.Context) error {
+	_, eth, err := w.f.EthClients()
+	if err != nil {
+		return err
+	}
+	head, err := eth.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	for w.next <= head {
+		to := min(w.next+1999, head)
+		query := w.query
+		query.FromBlock, query.ToBlock = new(big.Int).SetUint64(w.next), new(big.Int).SetUint64(to)
+		entries, err := eth.FilterLogs(ctx, query)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			w.deliver(entry)
+		}
+		w.next = to + 1
+	}
+	return nil
+}
+
+// deliver decodes entry and applies it, skipping logs already delivered by the subscription or a poll and removals
+// of logs never delivered. A removed log is forgotten, so its replacement after the reorg is delivered.
+func (w *watcher) deliver(entry types.Log) {
+	id := logID{block: entry.BlockHash, index: entry.Index}
+	_, seen := w.seen[id]
+	if entry.Removed {
+		if !seen {
+			return
+		}
+		delete(w.seen, id)
+	} else {
+		if seen {
+			return
+		}
+		w.seen[id] = entry.BlockNumber
+		// A later poll starts at the newest delivered block, within the logs still remembered, so a resubscription
+		// never replays logs forget has dropped.
+		w.next = max(w.next, entry.BlockNumber)
+		if entry.BlockNumber > w.highest {
+			w.highest = entry.BlockNumber
+			w.forget()
+		}
+	}
+
+	e, err := decodeEvent(w.event, entry)
+	if err != nil {
+		log.Printf("Failed to decode %s event in transaction %s: %v", w.event.Name, entry.TxHash, err)
+		return
+	}
+	w.apply(w.f.Zero, e)
+}
+
+// forget drops the logs more than watchDepth blocks below the highest delivered one.
+func (w *watcher) forget() {
+	if w.highest < watchDepth {
+		return
+	}
+	for id, number := range w.seen {
+		if number < w.highest-watchDepth {
+			delete(w.seen, id)
+		}
+	}
+}
+
+// decodeEvent unpacks the indexed arguments from the topics and the others from the data of entry.
+func decodeEvent(event abi.Event, entry types.Log) (Event, error) {
+	e := Event{Name: event.Name, Args: make(map[string]any), Log: entry}
+	if len(entry.Topics) == 0 || entry.Topics[0] != event.ID {
+		return e, errors.New("log does not match the event signature")
+	}
+	if err := event.Inputs.NonIndexed().UnpackIntoMap(e.Args, entry.Data); err != nil {
+		return e, err
+	}
+	var indexed abi.Arguments
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		}
+	}
+	if err := abi.ParseTopicsIntoMap(e.Args, indexed, entry.Topics[1:]); err != nil {
+		return e, err
+	}
+	return e, nil
+}
+
+// parseEvent builds an event from a human readable signature, with or without the event keyword, argument names
+// and indexed markers. Tuple arguments are not supported.
+func parseEvent(signature string) (abi.Event, error) {
+	signature = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(signature), "event "))
+	name, rest, ok := strings.Cut(signature, "(")
+	if !ok || !strings.HasSuffix(rest, ")") || name == "" {
+		return abi.Event{}, fmt.Errorf("invalid event signature %q", signature)
+	}
+	rest = strings.TrimSuffix(rest, ")")
+	if strings.ContainsAny(rest, "()") {
+		return abi.Event{}, fmt.Errorf("event signature %q: tuple arguments are not supported", signature)
+	}
+
+	var inputs abi.Arguments
+	if strings.TrimSpace(rest) != "" {
+		for part := range strings.SplitSeq(rest, ",") {
+			fields := strings.Fields(part)
+			if len(fields) == 0 || len(fields) > 3 {
+				return abi.Event{}, fmt.Errorf("event signature %q: invalid argument %q", signature, part)
+			}
+			typ, err := abi.NewType(fields[0], "", nil)
+			if err != nil {
+				return abi.Event{}, fmt.Errorf("event signature %q: %w", signature, err)
+			}
+			input := abi.Argument{Type: typ}
+			fields = fields[1:]
+			if len(fields) > 0 && fields[0] == "indexed" {
+				input.Indexed = true
+				fields = fields[1:]
+			}
+			switch len(fields) {
+			case 0:
+			case 1:
+				input.Name = fields[0]
+			default:
+				return abi.Event{}, fmt.Errorf("event signature %q: invalid argument %q", signature, part)
+			}
+			inputs = append(inputs, input)
+		}
+	}
+	name = strings.TrimSpace(name)
+	return abi.NewEvent(name, name, false, inputs), nil
+}
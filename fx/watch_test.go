@@ -0,0 +1,143 @@
+package fx
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/zachklingbeil/factory/zero"
+)
+
+// fakeChain answers eth_blockNumber and eth_getLogs with one log per block in logs.
+type fakeChain struct {
+	sync.Mutex
+	head uint64
+	logs []types.Log
+}
+
+func (c *fakeChain) BlockNumber() hexutil.Uint64 {
+	c.Lock()
+	defer c.Unlock()
+	return hexutil.Uint64(c.head)
+}
+
+func (c *fakeChain) GetLogs(filter struct {
+	FromBlock *hexutil.Big `json:"fromBlock"`
+	ToBlock   *hexutil.Big `json:"toBlock"`
+}) []types.Log {
+	c.Lock()
+	defer c.Unlock()
+	logs := []types.Log{}
+	for _, entry := range c.logs {
+		if entry.BlockNumber >= filter.FromBlock.ToInt().Uint64() && entry.BlockNumber <= filter.ToBlock.ToInt().Uint64() {
+			logs = append(logs, entry)
+		}
+	}
+	return logs
+}
+
+func (c *fakeChain) emit(event common.Hash, number uint64) types.Log {
+	entry := types.Log{
+		Topics:      []common.Hash{event},
+		Data:        common.LeftPadBytes(big.NewInt(int64(number)).Bytes(), 32),
+		BlockNumber: number,
+		BlockHash:   common.BigToHash(new(big.Int).SetUint64(number)),
+		TxHash:      common.BigToHash(new(big.Int).SetUint64(number + 1<<32)),
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.logs = append(c.logs, entry)
+	c.head = max(c.head, number)
+	return entry
+}
+
+func TestWatcherResubscribeDoesNotReplay(t *testing.T) {
+	chain := &fakeChain{}
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", chain); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	f := Init()
+	f.node.Store(&node{rpc: client, eth: ethclient.NewClient(client), stop: func() {}})
+
+	event, err := parseEvent("Ping(uint256 n)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied := make(map[uint64]int)
+	w := &watcher{
+		f:     f,
+		event: event,
+		apply: func(z *zero.Zero, e Event) { applied[e.Log.BlockNumber]++ },
+		next:  1,
+		seen:  make(map[logID]uint64),
+	}
+
+	// The first poll delivers the existing log, then the subscription delivers logs far past the dedup window.
+	chain.emit(event.ID, 10)
+	if err := w.poll(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	for number := uint64(200); number <= 400; number++ {
+		w.deliver(chain.emit(event.ID, number))
+	}
+
+	// A resubscription polls again before following the new subscription.
+	if err := w.poll(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(applied) != 202 {
+		t.Errorf("applied logs of %d blocks, want 202", len(applied))
+	}
+	for number, count := range applied {
+		if count != 1 {
+			t.Errorf("log of block %d applied %d times", number, count)
+		}
+	}
+}
+
+func TestWatcherRedeliversAfterReorg(t *testing.T) {
+	event, err := parseEvent("Ping(uint256 n)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	w := &watcher{
+		f:     Init(),
+		event: event,
+		apply: func(z *zero.Zero, e Event) {
+			if e.Log.Removed {
+				count--
+			} else {
+				count++
+			}
+		},
+		seen: make(map[logID]uint64),
+	}
+
+	chain := &fakeChain{}
+	orphaned := chain.emit(event.ID, 5)
+	w.deliver(orphaned)
+	w.deliver(orphaned)
+	removed := orphaned
+	removed.Removed = true
+	w.deliver(removed)
+
+	replacement := orphaned
+	replacement.BlockHash = common.HexToHash("0x5a")
+	w.deliver(replacement)
+
+	if count != 1 {
+		t.Errorf("count is %d after a reorg replaced the log, want 1", count)
+	}
+}
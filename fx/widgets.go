@@ -0,0 +1,253 @@
+package fx
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/zachklingbeil/factory/zero"
+)
+
+// LatestBlockKey is the Zero key holding the latest block number while live widgets run.
+const LatestBlockKey = "block"
+
+// WidgetOption configures the Ethereum widgets.
+type WidgetOption func(*widgetConfig)
+
+type widgetConfig struct {
+	live context.Context
+}
+
+// WithLive renders the widget again on every new block until ctx ends, replacing the content of the returned One,
+// so a frame added with AddFrame stays current. New blocks are published to Zero under LatestBlockKey.
+func WithLive(ctx context.Context) WidgetOption {
+	return func(c *widgetConfig) {
+		c.live = ctx
+	}
+}
+
+// AddressCard renders the checksummed address, whether it is a contract, its transaction count and its balance.
+func (f *Fx) AddressCard(ctx context.Context, address common.Address, options ...WidgetOption) (*zero.One, error) {
+	return f.widget(ctx, options, func(ctx context.Context) (*zero.One, error) {
+		_, eth, err := f.EthClients()
+		if err != nil {
+			return nil, err
+		}
+		balance, err := eth.BalanceAt(ctx, address, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the balance of %s: %w", address, err)
+		}
+		nonce, err := eth.NonceAt(ctx, address, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the nonce of %s: %w", address, err)
+		}
+		code, err := eth.CodeAt(ctx, address, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the code of %s: %w", address, err)
+		}
+
+		kind := "account"
+		if len(code) > 0 {
+			kind = "contract"
+		}
+		e := f.Zero.Element
+		rows := [][]string{
+			{"type", kind},
+			{"balance", formatUnits(balance, 18) + " ETH"},
+			{"transactions", fmt.Sprint(nonce)},
+		}
+		return f.Lego("eth-address", *e.H3(address.Hex()), *e.Table(2, uint64(len(rows)), rows)), nil
+	})
+}
+
+// BlockTicker renders the latest block: number, hash, time, transaction count, gas used and base fee.
+func (f *Fx) BlockTicker(ctx context.Context, options ...WidgetOption) (*zero.One, error) {
+	return f.widget(ctx, options, func(ctx context.Context) (*zero.One, error) {
+		_, eth, err := f.EthClients()
+		if err != nil {
+			return nil, err
+		}
+		block, err := eth.BlockByNumber(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the latest block: %w", err)
+		}
+
+		e := f.Zero.Element
+		rows := [][]string{
+			{"hash", block.Hash().Hex()},
+			{"transactions", fmt.Sprint(len(block.Transactions()))},
+			{"gas used", fmt.Sprintf("%d of %d", block.GasUsed(), block.GasLimit())},
+		}
+		if block.BaseFee() != nil {
+			rows = append(rows, []string{"base fee", formatUnits(block.BaseFee(), 9) + " gwei"})
+		}
+		return f.Lego("eth-block",
+			*e.H3(fmt.Sprintf("block %d", block.NumberU64())),
+			*e.Time(time.Unix(int64(block.Time()), 0).UTC().Format(time.RFC3339)),
+			*e.Table(2, uint64(len(rows)), rows),
+		), nil
+	})
+}
+
+// TransactionView renders a transaction with its status, confirmations, parties, value and fee.
+func (f *Fx) TransactionView(ctx context.Context, hash common.Hash, options ...WidgetOption) (*zero.One, error) {
+	return f.widget(ctx, options, func(ctx context.Context) (*zero.One, error) {
+		_, eth, err := f.EthClients()
+		if err != nil {
+			return nil, err
+		}
+		tx, pending, err := eth.TransactionByHash(ctx, hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read transaction %s: %w", hash, err)
+		}
+
+		rows := [][]string{{"status", "pending"}}
+		var receipt *types.Receipt
+		if !pending {
+			if receipt, err = eth.TransactionReceipt(ctx, hash); err != nil {
+				return nil, fmt.Errorf("failed to read the receipt of %s: %w", hash, err)
+			}
+			head, err := eth.BlockNumber(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the head block: %w", err)
+			}
+			status := "success"
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				status = "failed"
+			}
+			rows = [][]string{
+				{"status", status},
+				{"block", fmt.Sprint(receipt.BlockNumber)},
+				{"confirmations", fmt.Sprint(head - receipt.BlockNumber.Uint64() + 1)},
+			}
+		}
+
+		var blockHash common.Hash
+		var index uint
+		if receipt != nil {
+			blockHash, index = receipt.BlockHash, receipt.TransactionIndex
+		}
+		if from, err := eth.TransactionSender(ctx, tx, blockHash, index); err == nil {
+			rows = append(rows, []string{"from", from.Hex()})
+		}
+		switch {
+		case tx.To() != nil:
+			rows = append(rows, []string{"to", tx.To().Hex()})
+		case receipt != nil:
+			rows = append(rows, []string{"created", receipt.ContractAddress.Hex()})
+		}
+		rows = append(rows, []string{"value", formatUnits(tx.Value(), 18) + " ETH"})
+		if receipt != nil && receipt.EffectiveGasPrice != nil {
+			fee := new(big.Int).Mul(receipt.EffectiveGasPrice, new(big.Int).SetUint64(receipt.GasUsed))
+			rows = append(rows,
+				[]string{"gas used", fmt.Sprint(receipt.GasUsed)},
+				[]string{"gas price", formatUnits(receipt.EffectiveGasPrice, 9) + " gwei"},
+				[]string{"fee", formatUnits(fee, 18) + " ETH"},
+			)
+		}
+
+		e := f.Zero.Element
+		return f.Lego("eth-tx", *e.H3(hash.Hex()), *e.Table(2, uint64(len(rows)), rows)), nil
+	})
+}
+
+// widget renders once and, when live, again after each new block.
+func (f *Fx) widget(ctx context.Context, options []WidgetOption, render func(context.Context) (*zero.One, error)) (*zero.One, error) {
+	config := &widgetConfig{}
+	for _, option := range options {
+		option(config)
+	}
+	frame, err := render(ctx)
+	if err != nil || config.live == nil {
+		return frame, err
+	}
+
+	f.headsOnce.Do(func() { go f.followHeads() })
+	blocks, stop := f.Zero.Watch(LatestBlockKey)
+	go func() {
+		defer stop()
+		for {
+			select {
+			case <-config.live.Done():
+				return
+			case <-blocks:
+			}
+			next, err := render(config.live)
+			if err != nil {
+				if config.live.Err() == nil {
+					log.Printf("Failed to refresh widget: %v", err)
+				}
+				continue
+			}
+			f.Zero.Replace(frame, *next)
+		}
+	}()
+	return frame, nil
+}
+
+// followHeads publishes the latest block number to Zero until the Fx context ends, pushed by a head subscription
+// when the endpoint supports one and polled otherwise.
+func (f *Fx) followHeads() {
+	heads := make(chan *types.Header, 16)
+	var sub ethereum.Subscription
+	defer func() {
+		if sub != nil {
+			sub.Unsubscribe()
+		}
+	}()
+	ticker := time.NewTicker(12 * time.Second)
+	defer ticker.Stop()
+
+	latest := uint64(0)
+	for {
+		_, eth, err := f.EthClients()
+		if err == nil {
+			if sub == nil {
+				if sub, err = eth.SubscribeNewHead(f.Context, heads); err != nil {
+					sub = nil
+				}
+			}
+			if number, err := eth.BlockNumber(f.Context); err == nil && number != latest {
+				latest = number
+				f.Zero.Add(LatestBlockKey, int(number))
+			}
+		}
+		var subErr <-chan error
+		if sub != nil {
+			subErr = sub.Err()
+		}
+
+		select {
+		case <-f.Context.Done():
+			return
+		case <-heads:
+		case <-ticker.C:
+		case <-subErr:
+			sub = nil
+		}
+	}
+}
+
+// formatUnits renders value divided by 10^decimals, with at most 6 fractional digits and trailing zeros removed.
+func formatUnits(value *big.Int, decimals int) string {
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	whole, fraction := new(big.Int).QuoRem(new(big.Int).Abs(value), unit, new(big.Int))
+
+	digits := fmt.Sprintf("%0*s", decimals, fraction.String())
+	digits = strings.TrimRight(digits[:min(len(digits), 6)], "0")
+	result := whole.String()
+	if digits != "" {
+		result += "." + digits
+	}
+	if value.Sign() < 0 {
+		result = "-" + result
+	}
+	return result
+}
@@ -37,6 +37,8 @@ func (o *Factory) Pathless(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Y", strconv.Itoa(current))
 	w.Header().Set("Z", strconv.Itoa(next))
 
-	frame := o.Frames[current]
-	fmt.Fprint(w, *frame)
+	o.RLock()
+	frame := *o.Frames[current]
+	o.RUnlock()
+	fmt.Fprint(w, frame)
 }
@@ -3,12 +3,32 @@ package zero
 import (
 	"encoding/json"
 	"os"
+	"slices"
 	"sync/atomic"
 )
 
 func (z *Zero) Add(key string, val int) {
 	z.Lock()
 	defer z.Unlock()
+	z.store(key, val)
+}
+
+// Increment adds delta to the int at key, which starts from 0, and returns the result.
+func (z *Zero) Increment(key string, delta int) int {
+	z.Lock()
+	defer z.Unlock()
+	val := delta
+	if v, exists := z.Map[key]; exists {
+		if i, ok := v.Load().(int); ok {
+			val += i
+		}
+	}
+	z.store(key, val)
+	return val
+}
+
+// store sets key and notifies its watchers. It must be called with the lock held.
+func (z *Zero) store(key string, val int) {
 	if _, exists := z.Map[key]; !exists {
 		z.Map[key] = &atomic.Value{}
 	}
@@ -35,6 +55,20 @@ func (z *Zero) Observe(key string) <-chan int {
 	return ch
 }
 
+// Watch returns a channel receiving the value of key after each change and a function to stop watching.
+// A change is dropped while the channel still holds the previous one.
+func (z *Zero) Watch(key string) (<-chan any, func()) {
+	ch := make(chan any, 1)
+	z.Lock()
+	z.watchers[key] = append(z.watchers[key], ch)
+	z.Unlock()
+	return ch, func() {
+		z.Lock()
+		defer z.Unlock()
+		z.watchers[key] = slices.DeleteFunc(z.watchers[key], func(c chan any) bool { return c == ch })
+	}
+}
+
 func (z *Zero) Subtract(key string) {
 	z.Lock()
 	defer z.Unlock()
@@ -45,3 +45,10 @@ func (z *Zero) AddFrame(frame *One) {
 	z.Frames = append(z.Frames, frame)
 	z.Add("count", len(z.Frames))
 }
+
+// Replace swaps the content of frame for content under the lock, so a frame can change while it is served.
+func (z *Zero) Replace(frame *One, content One) {
+	z.Lock()
+	*frame = content
+	z.Unlock()
+}